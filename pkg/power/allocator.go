@@ -0,0 +1,228 @@
+package power
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// CpuAllocation describes a request for CPUs to be taken from the shared pool by Host.AllocateCpus
+type CpuAllocation struct {
+	// number of CPUs to allocate
+	Count uint
+	// all allocated CPUs have to be on the same package, die or NUMA node
+	SamePackage  bool
+	SameDie      bool
+	SameNumaNode bool
+	// only allocate whole physical cores, all sibling threads of a core are allocated together
+	WholeCores bool
+	// only allocate CPUs of a specific core type, index into Host.GetFreqRanges(), nil allows any type
+	CoreType *uint
+	// skip CPUs with a CPU level C-States configuration that enables any of these states,
+	// CPU level configuration takes precedence over the pool so it stays in effect after the move
+	AvoidCStates []string
+}
+
+// cpuGroupKey identifies CPUs that are considered together when satisfying placement constraints
+// fields of constraints that were not requested are left as 0
+type cpuGroupKey struct {
+	pkg  uint
+	die  uint
+	numa uint
+}
+
+func (k cpuGroupKey) less(other cpuGroupKey) bool {
+	if k.pkg != other.pkg {
+		return k.pkg < other.pkg
+	}
+	if k.die != other.die {
+		return k.die < other.die
+	}
+	return k.numa < other.numa
+}
+
+// candidate cores within a single group, siblings of a core are kept together
+type cpuGroup struct {
+	key   cpuGroupKey
+	cores []CpuList
+}
+
+var numaNodeDirRegex = regexp.MustCompile(`^node(\d+)$`)
+
+// AllocateCpus selects CPUs from the shared pool that satisfy the request and moves them to the exclusive
+// pool with the given name. The pool is created if it does not exist yet. Selection is deterministic,
+// CPUs are picked core by core in order of package, die and CPU IDs. If moving fails, CPUs moved so far are returned
+// to the shared pool and a pool created by the allocation is removed
+func (host *hostImpl) AllocateCpus(poolName string, request CpuAllocation) (CpuList, error) {
+	if request.Count == 0 {
+		return nil, fmt.Errorf("cannot allocate 0 cpus")
+	}
+	pool, created := host.getOrAddExclusivePool(poolName)
+	cpus, err := host.allocateCpus(pool, request)
+	if err != nil && created {
+		err = errors.Join(err, pool.Remove())
+	}
+	return cpus, err
+}
+
+// allocateCpus selects and moves the CPUs while holding mutexes of the shared and target pools,
+// so concurrent allocations cannot pick the same CPUs
+func (host *hostImpl) allocateCpus(pool Pool, request CpuAllocation) (CpuList, error) {
+	sharedPool := host.GetSharedPool()
+	unlock := lockPools(sharedPool, pool)
+	defer unlock()
+
+	cpus, err := host.selectCpus(request)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("allocating cpus", "pool", pool.Name(), "cpus", cpus.IDs())
+	for i, cpu := range cpus {
+		if err := cpu.moveLocked(sharedPool, pool); err != nil {
+			err = fmt.Errorf("failed to allocate cpus to pool %s: %w", pool.Name(), err)
+			return nil, errors.Join(err, releaseLocked(cpus[:i], pool, sharedPool))
+		}
+	}
+	return cpus, nil
+}
+
+// moves cpus back to the shared pool after a failed allocation, caller holds mutexes of both pools
+func releaseLocked(cpus CpuList, pool Pool, sharedPool Pool) error {
+	allErrors := make([]error, 0)
+	for _, cpu := range cpus {
+		if err := cpu.moveLocked(pool, sharedPool); err != nil {
+			allErrors = append(allErrors, fmt.Errorf("failed to return cpu %d to shared pool: %w", cpu.GetID(), err))
+		}
+	}
+	return errors.Join(allErrors...)
+}
+
+// ReleaseCpus returns CPUs previously allocated to an exclusive pool back to the shared pool
+func (host *hostImpl) ReleaseCpus(poolName string, cpus CpuList) error {
+	pool := host.GetExclusivePool(poolName)
+	if pool == nil {
+		return &PoolNotFoundError{Name: poolName}
+	}
+	for _, cpu := range cpus {
+		if !pool.Cpus().Contains(cpu) {
+			return &CpuNotFoundError{CpuID: cpu.GetID(), Pool: poolName}
+		}
+	}
+	log.Info("releasing cpus", "pool", poolName, "cpus", cpus.IDs())
+	return host.GetSharedPool().MoveCpus(cpus)
+}
+
+// selectCpus picks CPUs from the shared pool satisfying the allocation request without moving them
+func (host *hostImpl) selectCpus(request CpuAllocation) (CpuList, error) {
+	sharedCpus := host.GetSharedPool().Cpus()
+	groups := map[cpuGroupKey]*cpuGroup{}
+
+	for _, pkg := range *host.Topology().Packages() {
+		for _, die := range *pkg.Dies() {
+			for _, core := range *die.Cores() {
+				coreCpus := make(CpuList, 0, len(*core.CPUs()))
+				for _, cpu := range *core.CPUs() {
+					if sharedCpus.Contains(cpu) && isCpuAllocatable(cpu, request) {
+						coreCpus.add(cpu)
+					}
+				}
+				if len(coreCpus) == 0 {
+					continue
+				}
+				if request.WholeCores && len(coreCpus) != len(*core.CPUs()) {
+					continue
+				}
+				sort.Slice(coreCpus, func(i, j int) bool { return coreCpus[i].GetID() < coreCpus[j].GetID() })
+
+				key := cpuGroupKey{}
+				if request.SamePackage || request.SameDie {
+					key.pkg = pkg.getID()
+				}
+				if request.SameDie {
+					key.die = die.getID()
+				}
+				if request.SameNumaNode {
+					node, err := readCpuNumaNode(coreCpus[0].GetID())
+					if err != nil {
+						return nil, err
+					}
+					key.numa = node
+				}
+				if _, exists := groups[key]; !exists {
+					groups[key] = &cpuGroup{key: key}
+				}
+				groups[key].cores = append(groups[key].cores, coreCpus)
+			}
+		}
+	}
+
+	sortedGroups := make([]*cpuGroup, 0, len(groups))
+	for _, group := range groups {
+		sort.Slice(group.cores, func(i, j int) bool { return group.cores[i][0].GetID() < group.cores[j][0].GetID() })
+		sortedGroups = append(sortedGroups, group)
+	}
+	sort.Slice(sortedGroups, func(i, j int) bool { return sortedGroups[i].key.less(sortedGroups[j].key) })
+
+	for _, group := range sortedGroups {
+		if cpus := group.take(request.Count, request.WholeCores); cpus != nil {
+			return cpus, nil
+		}
+	}
+	return nil, fmt.Errorf("%w in shared pool to satisfy allocation of %d cpus", ErrNotEnoughCpus, request.Count)
+}
+
+// take returns count CPUs from the group or nil if the group cannot satisfy the request
+func (g *cpuGroup) take(count uint, wholeCores bool) CpuList {
+	cpus := make(CpuList, 0, count)
+	for _, core := range g.cores {
+		remaining := count - uint(len(cpus))
+		if remaining == 0 {
+			break
+		}
+		if uint(len(core)) <= remaining {
+			cpus = append(cpus, core...)
+		} else if !wholeCores {
+			cpus = append(cpus, core[:remaining]...)
+		}
+	}
+	if uint(len(cpus)) != count {
+		return nil
+	}
+	return cpus
+}
+
+func isCpuAllocatable(cpu Cpu, request CpuAllocation) bool {
+	if request.CoreType != nil && cpu.GetCore().GetType() != *request.CoreType {
+		return false
+	}
+	if states := cpu.getCStates(); states != nil {
+		for _, name := range request.AvoidCStates {
+			if (*states)[name] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// reads the NUMA node of a cpu from the nodeN link in its sysfs directory
+func readCpuNumaNode(cpuID uint) (uint, error) {
+	entries, err := os.ReadDir(filepath.Join(basePath, fmt.Sprint("cpu", cpuID)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to determine NUMA node of cpu %d: %w", cpuID, err)
+	}
+	for _, entry := range entries {
+		if match := numaNodeDirRegex.FindStringSubmatch(entry.Name()); match != nil {
+			node, err := strconv.Atoi(match[1])
+			if err != nil {
+				return 0, fmt.Errorf("failed to determine NUMA node of cpu %d: %w", cpuID, err)
+			}
+			return uint(node), nil
+		}
+	}
+	return 0, fmt.Errorf("failed to determine NUMA node of cpu %d: no node link found", cpuID)
+}
@@ -0,0 +1,231 @@
+package power
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// creates a host on a fake topology of 2 packages, 2 dies per package, 2 cores per die and 2 threads per core
+// cpus 0-7 are first threads and 8-15 their siblings, dies of package 0 are NUMA node 0, package 1 is node 1
+func setupAllocatorTest() (*hostImpl, func()) {
+	cpufiles := map[string]map[string]string{}
+	for i := 0; i < 16; i++ {
+		cpufiles[fmt.Sprint("cpu", i)] = map[string]string{
+			"pkg":  fmt.Sprint(i % 8 / 4),
+			"die":  fmt.Sprint(i % 4 / 2),
+			"core": fmt.Sprint(i % 2),
+		}
+	}
+	teardown := setupTopologyTest(cpufiles)
+	for i := 0; i < 16; i++ {
+		if err := os.MkdirAll(filepath.Join(basePath, fmt.Sprint("cpu", i), fmt.Sprint("node", i%8/4)), os.ModePerm); err != nil {
+			panic(err)
+		}
+	}
+	topology, err := discoverTopology()
+	if err != nil {
+		panic(err)
+	}
+	host := &hostImpl{topology: topology}
+	host.reservedPool = &reservedPoolType{poolImpl{name: reservedPoolName, mutex: &sync.Mutex{}, host: host}}
+	host.sharedPool = &sharedPoolType{poolImpl{name: sharedPoolName, mutex: &sync.Mutex{}, host: host}}
+	for _, cpu := range *topology.CPUs() {
+		cpu._setPoolProperty(host.sharedPool)
+		host.sharedPool.Cpus().add(cpu)
+	}
+	return host, teardown
+}
+
+func TestHostImpl_AllocateCpus(t *testing.T) {
+	host, teardown := setupAllocatorTest()
+	defer teardown()
+
+	// no constraints, siblings are allocated together
+	cpus, err := host.AllocateCpus("pool1", CpuAllocation{Count: 4})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{0, 8, 1, 9}, cpus.IDs())
+	pool := host.GetExclusivePool("pool1")
+	assert.NotNil(t, pool)
+	assert.ElementsMatch(t, cpus, *pool.Cpus())
+	assert.Len(t, *host.GetSharedPool().Cpus(), 12)
+
+	// existing pool is reused
+	cpus, err = host.AllocateCpus("pool1", CpuAllocation{Count: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{2}, cpus.IDs())
+	assert.Len(t, *pool.Cpus(), 5)
+	assert.Len(t, host.exclusivePools, 1)
+
+	// whole cores skip the core with a sibling already taken
+	cpus, err = host.AllocateCpus("pool2", CpuAllocation{Count: 2, WholeCores: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{3, 11}, cpus.IDs())
+
+	// same die, die 0 of package 1 is the first with 4 free cpus
+	cpus, err = host.AllocateCpus("pool3", CpuAllocation{Count: 4, SameDie: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{4, 12, 5, 13}, cpus.IDs())
+
+	// same package, only package 1 has cpus left on both dies
+	cpus, err = host.AllocateCpus("pool4", CpuAllocation{Count: 4, SamePackage: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{6, 14, 7, 15}, cpus.IDs())
+
+	// not enough cpus left, nothing is created
+	_, err = host.AllocateCpus("pool5", CpuAllocation{Count: 2, SamePackage: true})
+	assert.ErrorIs(t, err, ErrNotEnoughCpus)
+	assert.Nil(t, host.GetExclusivePool("pool5"))
+
+	_, err = host.AllocateCpus("pool5", CpuAllocation{})
+	assert.Error(t, err)
+}
+
+func TestHostImpl_AllocateCpusConcurrent(t *testing.T) {
+	host, teardown := setupAllocatorTest()
+	defer teardown()
+	// moves write the latency limit slowly so allocations overlap
+	for i := 0; i < 16; i++ {
+		assert.NoError(t, os.MkdirAll(filepath.Join(basePath, fmt.Sprint("cpu", i), "power"), os.ModePerm))
+	}
+	featureList[PMQoSFeature].err = nil
+	defer func() { featureList[PMQoSFeature].err = uninitialisedErr }()
+	sysfsWrites.reset(false)
+	defer sysfsWrites.reset(false)
+	origWriteFile := writeSysfsFile
+	defer func() { writeSysfsFile = origWriteFile }()
+	writeSysfsFile = func(name string, data []byte, perm os.FileMode) error {
+		time.Sleep(5 * time.Millisecond)
+		return origWriteFile(name, data, perm)
+	}
+
+	results := make([]CpuList, 4)
+	errs := make([]error, 4)
+	wg := sync.WaitGroup{}
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = host.AllocateCpus(fmt.Sprint("pool", i), CpuAllocation{Count: 4})
+		}(i)
+	}
+	wg.Wait()
+
+	allocated := CpuList{}
+	for i, cpus := range results {
+		assert.NoError(t, errs[i])
+		assert.ElementsMatch(t, cpus, *host.GetExclusivePool(fmt.Sprint("pool", i)).Cpus())
+		allocated = append(allocated, cpus...)
+	}
+	assert.ElementsMatch(t, *host.GetAllCpus(), allocated)
+	assert.Empty(t, *host.GetSharedPool().Cpus())
+}
+
+func TestHostImpl_AllocateCpusMoveFailure(t *testing.T) {
+	host, teardown := setupAllocatorTest()
+	defer teardown()
+	// latency limit file is missing so consolidation of moved cpus fails
+	featureList[PMQoSFeature].err = nil
+	defer func() { featureList[PMQoSFeature].err = uninitialisedErr }()
+
+	_, err := host.AllocateCpus("pool1", CpuAllocation{Count: 2})
+	assert.ErrorIs(t, err, ErrSysfsWrite)
+	assert.ErrorContains(t, err, "failed to allocate cpus to pool pool1")
+	assert.Nil(t, host.GetExclusivePool("pool1"))
+	assert.Len(t, *host.GetSharedPool().Cpus(), 16)
+}
+
+func TestHostImpl_AllocateCpusPartialMoveFailure(t *testing.T) {
+	host, teardown := setupAllocatorTest()
+	defer teardown()
+	existing, err := host.AllocateCpus("pool1", CpuAllocation{Count: 1})
+	assert.NoError(t, err)
+	// latency limit file exists only for the first two cpus selected next, the third one fails to move
+	featureList[PMQoSFeature].err = nil
+	defer func() { featureList[PMQoSFeature].err = uninitialisedErr }()
+	for _, id := range []uint{8, 1} {
+		assert.NoError(t, os.MkdirAll(filepath.Join(basePath, fmt.Sprint("cpu", id), "power"), os.ModePerm))
+	}
+
+	cpus, err := host.AllocateCpus("pool1", CpuAllocation{Count: 4})
+	assert.ErrorIs(t, err, ErrSysfsWrite)
+	assert.Nil(t, cpus)
+	// cpus moved before the failure are back in the shared pool, the existing pool is kept
+	pool := host.GetExclusivePool("pool1")
+	assert.NotNil(t, pool)
+	assert.ElementsMatch(t, existing, *pool.Cpus())
+	assert.Len(t, *host.GetSharedPool().Cpus(), 15)
+	for _, id := range []uint{8, 1} {
+		assert.Equal(t, host.GetSharedPool(), host.GetAllCpus().ByID(id).getPool())
+	}
+}
+
+func TestHostImpl_AllocateCpusFilters(t *testing.T) {
+	host, teardown := setupAllocatorTest()
+	defer teardown()
+
+	// numa node
+	cpus, err := host.AllocateCpus("pool1", CpuAllocation{Count: 6, SameNumaNode: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{0, 8, 1, 9, 2, 10}, cpus.IDs())
+	_, err = host.AllocateCpus("pool2", CpuAllocation{Count: 6, SameNumaNode: true})
+	assert.NoError(t, err)
+	assert.NoError(t, host.ReleaseCpus("pool1", cpus))
+	assert.NoError(t, host.ReleaseCpus("pool2", *host.GetExclusivePool("pool2").Cpus()))
+
+	// core type
+	(*host.GetAllCpus())[0].GetCore().setType(1)
+	coreType := uint(1)
+	cpus, err = host.AllocateCpus("pool1", CpuAllocation{Count: 2, CoreType: &coreType})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{0, 8}, cpus.IDs())
+	_, err = host.AllocateCpus("pool1", CpuAllocation{Count: 1, CoreType: &coreType})
+	assert.Error(t, err)
+	assert.NoError(t, host.ReleaseCpus("pool1", cpus))
+	(*host.GetAllCpus())[0].GetCore().setType(0)
+
+	// c-states overrides
+	(*host.GetAllCpus())[0].(*cpuImpl).cStates = &CStates{"C6": true}
+	(*host.GetAllCpus())[8].(*cpuImpl).cStates = &CStates{"C6": false}
+	cpus, err = host.AllocateCpus("pool1", CpuAllocation{Count: 2, AvoidCStates: []string{"C6"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{1, 9}, cpus.IDs())
+}
+
+func TestHostImpl_ReleaseCpus(t *testing.T) {
+	host, teardown := setupAllocatorTest()
+	defer teardown()
+
+	cpus, err := host.AllocateCpus("pool1", CpuAllocation{Count: 4})
+	assert.NoError(t, err)
+
+	err = host.ReleaseCpus("not existing", cpus)
+	assert.ErrorIs(t, err, ErrPoolNotFound)
+	assert.EqualError(t, err, "exclusive pool not existing does not exist")
+	err = host.ReleaseCpus("pool1", *host.GetSharedPool().Cpus())
+	assert.ErrorIs(t, err, ErrCpuNotFound)
+	assert.ErrorContains(t, err, "is not in pool pool1")
+
+	assert.NoError(t, host.ReleaseCpus("pool1", cpus[:2]))
+	assert.ElementsMatch(t, cpus[2:], *host.GetExclusivePool("pool1").Cpus())
+	assert.Len(t, *host.GetSharedPool().Cpus(), 14)
+}
+
+func TestReadCpuNumaNode(t *testing.T) {
+	defer setupTopologyTest(map[string]map[string]string{"cpu0": {}, "cpu1": {}})()
+	os.MkdirAll(filepath.Join(basePath, "cpu0", "node3"), os.ModePerm)
+
+	node, err := readCpuNumaNode(0)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), node)
+
+	_, err = readCpuNumaNode(1)
+	assert.ErrorContains(t, err, "no node link found")
+	_, err = readCpuNumaNode(2)
+	assert.Error(t, err)
+}
@@ -1,10 +1,13 @@
 package power
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -13,11 +16,29 @@ const (
 	cStatesDir           = "cpuidle"
 	cStateDisableFileFmt = cStatesDir + "/state%d/disable"
 	cStateNameFileFmt    = cStatesDir + "/state%d/name"
+	cStateDescFileFmt    = cStatesDir + "/state%d/desc"
+	cStateLatencyFileFmt = cStatesDir + "/state%d/latency"
+	cStateResidencyFmt   = cStatesDir + "/state%d/residency"
+	cStatePowerFileFmt   = cStatesDir + "/state%d/power"
 	cStatesDrvPath       = cStatesDir + "/current_driver"
 )
 
 type CStates map[string]bool
 
+// CStateInfo describes an idle state as reported by the cpuidle driver
+type CStateInfo struct {
+	Name string
+	Desc string
+	// state number in cpuidle/stateN
+	Number int
+	// exit latency in microseconds
+	Latency uint
+	// target residency in microseconds
+	Residency uint
+	// power consumption in milliwatts, as reported by the driver, often not provided
+	Power uint
+}
+
 func isSupportedCStatesDriver(driver string) bool {
 	for _, s := range []string{"intel_idle", "acpi_idle"} {
 		if driver == s {
@@ -34,10 +55,23 @@ var cStatesNamesMap = map[string]int{}
 // populated when mapping CStates
 var defaultCStates = CStates{}
 
+// map of c-state name to its metadata, populated when mapping CStates
+var cStatesInfoMap = map[string]CStateInfo{}
+
+// errors of c-states whose exit latency or target residency could not be read, populated when mapping CStates
+var cStatesInfoErrs = map[string]error{}
+
+// map of c-state name to state number for each cpu, populated when mapping CStates.
+// hybrid parts can expose different states on different core types
+var cpuCStatesNamesMaps = map[uint]map[string]int{}
+
+var cStateDirNameRegex = regexp.MustCompile(`state(\d+)`)
+
 func initCStates() featureStatus {
 	feature := featureStatus{
 		name:     "C-States",
 		initFunc: initCStates,
+		details:  cStatesDetails,
 	}
 	driver, err := readStringFromFile(filepath.Join(basePath, cStatesDrvPath))
 	driver = strings.TrimSuffix(driver, "\n")
@@ -55,73 +89,238 @@ func initCStates() featureStatus {
 	return feature
 }
 
-// sets cStatesNamesMap and defaultCStates
+func cStatesDetails() featureDetails {
+	states := make([]string, 0, len(cStatesNamesMap))
+	for name := range cStatesNamesMap {
+		states = append(states, name)
+	}
+	sort.Slice(states, func(i, j int) bool { return cStatesNamesMap[states[i]] < cStatesNamesMap[states[j]] })
+	return featureDetails{
+		paths: []string{
+			filepath.Join(basePath, cStatesDrvPath),
+			allCpusPath(cStatesDir + "/state*/disable"),
+		},
+		capabilities: map[string]any{"states": states},
+	}
+}
+
+// sets cStatesNamesMap, defaultCStates and the per cpu maps. cStatesNamesMap contains states of all cpus,
+// numbered as on the first cpu exposing them. failing to map cpu0 is fatal, other cpus fall back to cStatesNamesMap
 func mapAvailableCStates() error {
-	dirs, err := os.ReadDir(filepath.Join(basePath, "cpu0", cStatesDir))
+	numCpus := getNumberOfCpus()
+	for cpuID := uint(0); cpuID == 0 || cpuID < numCpus; cpuID++ {
+		states, err := readCpuCStates(cpuID)
+		if err != nil {
+			if cpuID == 0 {
+				return err
+			}
+			log.Info("could not map C-States, using system wide states", "cpu", cpuID, "error", err)
+			continue
+		}
+		cpuCStatesNamesMaps[cpuID] = states
+		for name, number := range states {
+			if _, exists := cStatesNamesMap[name]; exists {
+				continue
+			}
+			cStatesNamesMap[name] = number
+			defaultCStates[name] = true
+			info, err := readCStateInfo(cpuID, name, number)
+			if err != nil {
+				log.Info("could not read C-State metadata", "state", name, "cpu", cpuID, "error", err)
+				cStatesInfoErrs[name] = err
+			}
+			cStatesInfoMap[name] = info
+		}
+	}
+	log.V(3).Info("mapped C-states", "map", cStatesNamesMap)
+	return nil
+}
+
+// returns map of c-state names to state numbers of a single cpu
+func readCpuCStates(cpuID uint) (map[string]int, error) {
+	dirs, err := os.ReadDir(filepath.Join(basePath, fmt.Sprint("cpu", cpuID), cStatesDir))
 	if err != nil {
-		return fmt.Errorf("could not open cpu0 C-States directory: %w", err)
+		return nil, fmt.Errorf("could not open cpu%d C-States directory: %w", cpuID, err)
 	}
 
-	cStateDirNameRegex := regexp.MustCompile(`state(\d+)`)
+	states := map[string]int{}
 	for _, stateDir := range dirs {
 		dirName := stateDir.Name()
 		if !stateDir.IsDir() || !cStateDirNameRegex.MatchString(dirName) {
-			log.Info("map C-States ignoring " + dirName)
+			log.V(3).Info("map C-States ignoring "+dirName, "cpu", cpuID)
 			continue
 		}
 		stateNumber, err := strconv.Atoi(cStateDirNameRegex.FindStringSubmatch(dirName)[1])
 		if err != nil {
-			return fmt.Errorf("failed to extract C-State number %s: %w", dirName, err)
+			return nil, fmt.Errorf("failed to extract C-State number %s: %w", dirName, err)
 		}
 
-		stateName, err := readCpuStringProperty(0, fmt.Sprintf(cStateNameFileFmt, stateNumber))
+		stateName, err := readCpuStringProperty(cpuID, fmt.Sprintf(cStateNameFileFmt, stateNumber))
 		if err != nil {
-			return fmt.Errorf("could not read C-State %d name: %w", stateNumber, err)
+			return nil, fmt.Errorf("could not read C-State %d name: %w", stateNumber, err)
 		}
+		states[stateName] = stateNumber
+	}
+	return states, nil
+}
 
-		cStatesNamesMap[stateName] = stateNumber
-		defaultCStates[stateName] = true
+// returns the state number of a c-state on a specific cpu
+func cStateNumber(cpuID uint, name string) (int, bool) {
+	states, exists := cpuCStatesNamesMaps[cpuID]
+	if !exists {
+		states = cStatesNamesMap
 	}
-	log.V(3).Info("mapped C-states", "map", cStatesNamesMap)
-	return nil
+	number, exists := states[name]
+	return number, exists
+}
+
+// reads metadata of a c-state of a cpu. missing description and power are logged and left empty, missing latency
+// or residency is an error as 0 would make the state look like the shallowest one
+func readCStateInfo(cpuID uint, name string, number int) (CStateInfo, error) {
+	info := CStateInfo{Name: name, Number: number}
+	var err error
+	if info.Desc, err = readCpuStringProperty(cpuID, fmt.Sprintf(cStateDescFileFmt, number)); err != nil {
+		log.V(3).Info("could not read C-State description", "state", name, "error", err)
+	}
+	if info.Power, err = readCpuUintProperty(cpuID, fmt.Sprintf(cStatePowerFileFmt, number)); err != nil {
+		log.V(3).Info("could not read C-State power", "state", name, "error", err)
+	}
+	if info.Latency, err = readCpuUintProperty(cpuID, fmt.Sprintf(cStateLatencyFileFmt, number)); err != nil {
+		return info, fmt.Errorf("could not read exit latency of C-State %s: %w", name, err)
+	}
+	if info.Residency, err = readCpuUintProperty(cpuID, fmt.Sprintf(cStateResidencyFmt, number)); err != nil {
+		return info, fmt.Errorf("could not read target residency of C-State %s: %w", name, err)
+	}
+	return info, nil
+}
+
+// NewCStatesFromLatency creates C-States with all states that have exit latency of at most maxLatencyUs enabled
+// and all other states disabled
+func NewCStatesFromLatency(maxLatencyUs uint) (CStates, error) {
+	return newCStatesFromInfo(func(info CStateInfo) bool { return info.Latency <= maxLatencyUs })
+}
+
+// NewCStatesFromResidency creates C-States with all states whose target residency does not exceed the expected
+// idle duration enabled and all other states disabled
+func NewCStatesFromResidency(idleDurationUs uint) (CStates, error) {
+	return newCStatesFromInfo(func(info CStateInfo) bool { return info.Residency <= idleDurationUs })
+}
+
+func newCStatesFromInfo(enabled func(info CStateInfo) bool) (CStates, error) {
+	if !IsFeatureSupported(CStatesFeature) {
+		return nil, featureList.getFeatureIdError(CStatesFeature)
+	}
+	states := CStates{}
+	for name, info := range cStatesInfoMap {
+		if err, unknown := cStatesInfoErrs[name]; unknown {
+			return nil, err
+		}
+		states[name] = enabled(info)
+	}
+	return states, nil
 }
 
 func validateCStates(states CStates) error {
 	for name := range states {
 		if _, exists := cStatesNamesMap[name]; !exists {
-			return fmt.Errorf("c-state %s does not exist on this system", name)
+			return &CStateNotFoundError{Name: name}
 		}
 	}
 	return nil
 }
+
+// validateCStatesOnCpus checks that states exist on every cpu of the list, all missing states are reported
+// together with the cpus they are missing on
+func validateCStatesOnCpus(states CStates, cpus CpuList) error {
+	if err := validateCStates(states); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(states))
+	for name := range states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	allErrors := make([]error, 0)
+	for _, name := range names {
+		missing := make([]uint, 0)
+		for _, cpu := range cpus {
+			if _, exists := cStateNumber(cpu.GetID(), name); !exists {
+				missing = append(missing, cpu.GetID())
+			}
+		}
+		if len(missing) > 0 {
+			allErrors = append(allErrors, &CStateNotFoundError{Name: name, Cpus: missing})
+		}
+	}
+	return errors.Join(allErrors...)
+}
+
+// CStatesOption changes how SetCStates validates the states
+type CStatesOption func(*cStatesOptions)
+
+type cStatesOptions struct {
+	skipUnavailable bool
+}
+
+// SkipUnavailableCStates skips states not available on some of the target cpus instead of failing. meant for
+// C-States created by NewCStatesFromLatency or NewCStatesFromResidency, which cover states of all core types
+func SkipUnavailableCStates() CStatesOption {
+	return func(options *cStatesOptions) {
+		options.skipUnavailable = true
+	}
+}
+
+// validates states on the target cpus, only against states of the system if unavailable states are skipped
+func validateCStatesWithOptions(states CStates, cpus CpuList, opts []CStatesOption) error {
+	options := cStatesOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.skipUnavailable {
+		return validateCStates(states)
+	}
+	return validateCStatesOnCpus(states, cpus)
+}
+
 func (host *hostImpl) ValidateCStates(states CStates) error {
 	return validateCStates(states)
 }
 
-func (host *hostImpl) AvailableCStates() []string {
+// AvailableCStates returns metadata of all C-States on the system ordered by state number
+func (host *hostImpl) AvailableCStates() []CStateInfo {
 	if !featureList.isFeatureIdSupported(CStatesFeature) {
-		return []string{}
+		return []CStateInfo{}
 	}
-	cStatesList := make([]string, 0)
-	for name := range cStatesNamesMap {
-		cStatesList = append(cStatesList, name)
+	cStatesList := make([]CStateInfo, 0, len(cStatesNamesMap))
+	for name, number := range cStatesNamesMap {
+		info, exists := cStatesInfoMap[name]
+		if !exists {
+			info = CStateInfo{Name: name, Number: number}
+		}
+		cStatesList = append(cStatesList, info)
 	}
+	sort.Slice(cStatesList, func(i, j int) bool { return cStatesList[i].Number < cStatesList[j].Number })
 	return cStatesList
 }
 
-func (pool *poolImpl) SetCStates(states CStates) error {
+func (pool *poolImpl) SetCStates(states CStates, opts ...CStatesOption) error {
+	return pool.SetCStatesContext(context.Background(), states, opts...)
+}
+
+func (pool *poolImpl) SetCStatesContext(ctx context.Context, states CStates, opts ...CStatesOption) error {
 	if !IsFeatureSupported(CStatesFeature) {
 		return featureList.getFeatureIdError(CStatesFeature)
 	}
-	// check if requested states are on the system
-	if err := validateCStates(states); err != nil {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	// check if requested states are available on all cpus of the pool
+	if err := validateCStatesWithOptions(states, pool.cpus, opts); err != nil {
 		return err
 	}
+	previous := pool.CStatesProfile
 	pool.CStatesProfile = &states
-	for _, cpu := range pool.cpus {
-		if err := cpu.consolidate(); err != nil {
-			return fmt.Errorf("failed to apply c-states: %w", err)
-		}
+	if err := pool.consolidateContext(ctx, "set c-states", func() { pool.CStatesProfile = previous }); err != nil {
+		return fmt.Errorf("failed to apply c-states: %w", err)
 	}
 	return nil
 }
@@ -130,16 +329,22 @@ func (pool *poolImpl) getCStates() *CStates {
 	return pool.CStatesProfile
 }
 
-func (cpu *cpuImpl) SetCStates(cStates CStates) error {
+func (cpu *cpuImpl) SetCStates(cStates CStates, opts ...CStatesOption) error {
 	if !IsFeatureSupported(CStatesFeature) {
 		return featureList.getFeatureIdError(CStatesFeature)
 	}
-	if err := validateCStates(cStates); err != nil {
+	if err := validateCStatesWithOptions(cStates, CpuList{cpu}, opts); err != nil {
 		return err
 	}
+	unlock := cpu.lockWithPools()
+	defer unlock()
 	cpu.cStates = &cStates
 	return cpu.updateCStates()
 }
+
+func (cpu *cpuImpl) getCStates() *CStates {
+	return cpu.cStates
+}
 func (cpu *cpuImpl) updateCStates() error {
 	if !IsFeatureSupported(CStatesFeature) {
 		return nil
@@ -155,18 +360,22 @@ func (cpu *cpuImpl) updateCStates() error {
 
 func (cpu *cpuImpl) applyCStates(desiredCStates *CStates) error {
 	for state, enabled := range *desiredCStates {
+		// cpus moved into a pool or states set with SkipUnavailableCStates can lack some of the states
+		stateNumber, exists := cStateNumber(cpu.id, state)
+		if !exists {
+			log.V(3).Info("c-state not available on cpu, skipping", "cpu", cpu.id, "state", state)
+			continue
+		}
 		stateFilePath := filepath.Join(
 			basePath,
 			fmt.Sprint("cpu", cpu.id),
-			fmt.Sprintf(cStateDisableFileFmt, cStatesNamesMap[state]),
+			fmt.Sprintf(cStateDisableFileFmt, stateNumber),
 		)
-		content := make([]byte, 1)
+		content := "1" // write '1' to disable the c state
 		if enabled {
-			content[0] = '0' // write '0' to enable the c state
-		} else {
-			content[0] = '1' // write '1' to disable the c state
+			content = "0" // write '0' to enable the c state
 		}
-		if err := os.WriteFile(stateFilePath, content, 0644); err != nil {
+		if err := writeSysfsValue(stateFilePath, content); err != nil {
 			return fmt.Errorf("could not apply cstate %s on cpu %d: %w", state, cpu.id, err)
 		}
 	}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -13,6 +14,8 @@ import (
 func setupCpuCStatesTests(cpufiles map[string]map[string]map[string]string) func() {
 	origBasePath := basePath
 	basePath = "testing/cpus"
+	// fake files are recreated, cached values of previous tests do not apply
+	sysfsWrites.reset(false)
 
 	origGetNumOfCpusFunc := getNumberOfCpus
 	getNumberOfCpus = func() uint {
@@ -66,6 +69,9 @@ func setupCpuCStatesTests(cpufiles map[string]map[string]map[string]string) func
 		basePath = origBasePath
 		getNumberOfCpus = origGetNumOfCpusFunc
 		cStatesNamesMap = map[string]int{}
+		cStatesInfoMap = map[string]CStateInfo{}
+		cStatesInfoErrs = map[string]error{}
+		cpuCStatesNamesMaps = map[uint]map[string]int{}
 		featureList[CStatesFeature].err = uninitialisedErr
 	}
 }
@@ -113,6 +119,177 @@ func Test_mapAvailableCStates(t *testing.T) {
 	teardown()
 }
 
+func Test_mapAvailableCStatesInfo(t *testing.T) {
+	states := map[string]map[string]string{
+		"state0": {"name": "POLL", "desc": "CPUIDLE CORE POLL IDLE", "latency": "0", "residency": "0", "power": "4294967295"},
+		"state1": {"name": "C1", "desc": "MWAIT 0x00", "latency": "2", "residency": "2", "power": "0"},
+		"state2": {"name": "C6", "desc": "MWAIT 0x20", "latency": "170", "residency": "600", "power": "0"},
+		// metadata is optional
+		"state3": {"name": "C8"},
+	}
+	defer setupCpuCStatesTests(map[string]map[string]map[string]string{"cpu0": states})()
+
+	assert.NoError(t, mapAvailableCStates())
+	assert.Equal(t, map[string]CStateInfo{
+		"POLL": {Name: "POLL", Desc: "CPUIDLE CORE POLL IDLE", Number: 0, Power: 4294967295},
+		"C1":   {Name: "C1", Desc: "MWAIT 0x00", Number: 1, Latency: 2, Residency: 2},
+		"C6":   {Name: "C6", Desc: "MWAIT 0x20", Number: 2, Latency: 170, Residency: 600},
+		"C8":   {Name: "C8", Number: 3},
+	}, cStatesInfoMap)
+}
+
+func Test_mapAvailableCStatesHybrid(t *testing.T) {
+	// p-core
+	pStates := map[string]map[string]string{
+		"state0": {"name": "POLL", "disable": "0"},
+		"state1": {"name": "C1", "disable": "0"},
+		"state2": {"name": "C6", "disable": "0"},
+	}
+	// e-core with different numbering
+	eStates := map[string]map[string]string{
+		"state0": {"name": "POLL", "disable": "0"},
+		"state1": {"name": "C1", "disable": "0"},
+		"state2": {"name": "C1E", "disable": "0"},
+		"state3": {"name": "C6", "disable": "0"},
+	}
+	defer setupCpuCStatesTests(map[string]map[string]map[string]string{
+		"cpu0": pStates,
+		"cpu1": eStates,
+	})()
+
+	assert.NoError(t, mapAvailableCStates())
+	assert.Equal(t, map[string]int{"POLL": 0, "C1": 1, "C6": 2, "C1E": 2}, cStatesNamesMap)
+	assert.Equal(t, map[string]int{"POLL": 0, "C1": 1, "C1E": 2, "C6": 3}, cpuCStatesNamesMaps[1])
+	number, exists := cStateNumber(1, "C6")
+	assert.True(t, exists)
+	assert.Equal(t, 3, number)
+	_, exists = cStateNumber(0, "C1E")
+	assert.False(t, exists)
+
+	pool := &poolImpl{mutex: &sync.Mutex{}}
+	cpu0 := &cpuImpl{id: 0, mutex: &sync.Mutex{}, pool: pool}
+	cpu1 := &cpuImpl{id: 1, mutex: &sync.Mutex{}, pool: pool}
+	pool.cpus = CpuList{cpu0, cpu1}
+
+	// state written to the right index on each cpu
+	assert.NoError(t, pool.SetCStates(CStates{"C6": false}))
+	value, _ := readCpuStringProperty(0, fmt.Sprintf(cStateDisableFileFmt, 2))
+	assert.Equal(t, "1", value)
+	value, _ = readCpuStringProperty(1, fmt.Sprintf(cStateDisableFileFmt, 3))
+	assert.Equal(t, "1", value)
+	value, _ = readCpuStringProperty(1, fmt.Sprintf(cStateDisableFileFmt, 2))
+	assert.Equal(t, "0", value)
+
+	// state missing on some cpus of the pool
+	err := pool.SetCStates(CStates{"C1E": false})
+	assert.ErrorIs(t, err, ErrCStateNotFound)
+	assert.ErrorContains(t, err, "c-state C1E is not available on cpus [0]")
+	var notFound *CStateNotFoundError
+	assert.ErrorAs(t, err, &notFound)
+	assert.Equal(t, []uint{0}, notFound.Cpus)
+	assert.ErrorContains(t, cpu0.SetCStates(CStates{"C1E": false}), "not available on cpus [0]")
+	assert.NoError(t, cpu1.SetCStates(CStates{"C1E": false}))
+	assert.ErrorIs(t, cpu0.SetCStates(CStates{"C10": true}, SkipUnavailableCStates()), ErrCStateNotFound)
+
+	// unless skipped explicitly
+	assert.NoError(t, pool.SetCStates(CStates{"C1E": false}, SkipUnavailableCStates()))
+	value, _ = readCpuStringProperty(1, fmt.Sprintf(cStateDisableFileFmt, 2))
+	assert.Equal(t, "1", value)
+	assert.NoError(t, cpu0.SetCStates(CStates{"C1E": true}, SkipUnavailableCStates()))
+
+	// defaults skip states not available on the cpu
+	pool.CStatesProfile = nil
+	cpu0.cStates = nil
+	assert.NoError(t, cpu0.updateCStates())
+	value, _ = readCpuStringProperty(0, fmt.Sprintf(cStateDisableFileFmt, 2))
+	assert.Equal(t, "0", value)
+}
+
+func TestNewCStatesFromLatencyHybrid(t *testing.T) {
+	pStates := map[string]map[string]string{
+		"state0": {"name": "POLL", "latency": "0", "residency": "0", "disable": "0"},
+		"state1": {"name": "C1", "latency": "1", "residency": "1", "disable": "0"},
+		"state2": {"name": "C6", "latency": "170", "residency": "600", "disable": "0"},
+	}
+	eStates := map[string]map[string]string{
+		"state0": {"name": "POLL", "latency": "0", "residency": "0", "disable": "0"},
+		"state1": {"name": "C1", "latency": "1", "residency": "1", "disable": "0"},
+		"state2": {"name": "C1E", "latency": "10", "residency": "20", "disable": "0"},
+		"state3": {"name": "C6", "latency": "170", "residency": "600", "disable": "0"},
+	}
+	defer setupCpuCStatesTests(map[string]map[string]map[string]string{
+		"cpu0": pStates,
+		"cpu1": eStates,
+		"cpu2": pStates,
+	})()
+	assert.NoError(t, mapAvailableCStates())
+
+	states, err := NewCStatesFromLatency(20)
+	assert.NoError(t, err)
+	assert.Equal(t, CStates{"POLL": true, "C1": true, "C1E": true, "C6": false}, states)
+
+	// map covering states of all core types applies to pools of either type
+	pool := &poolImpl{mutex: &sync.Mutex{}}
+	cpus := CpuList{}
+	for id := uint(0); id < 3; id++ {
+		cpus = append(cpus, &cpuImpl{id: id, mutex: &sync.Mutex{}, pool: pool})
+	}
+	pool.cpus = CpuList{cpus[0], cpus[2]}
+	assert.ErrorIs(t, pool.SetCStates(states), ErrCStateNotFound)
+	assert.NoError(t, pool.SetCStates(states, SkipUnavailableCStates()))
+	value, _ := readCpuStringProperty(2, fmt.Sprintf(cStateDisableFileFmt, 2))
+	assert.Equal(t, "1", value)
+	assert.NoError(t, cpus[1].SetCStates(states, SkipUnavailableCStates()))
+	value, _ = readCpuStringProperty(1, fmt.Sprintf(cStateDisableFileFmt, 2))
+	assert.Equal(t, "0", value)
+	value, _ = readCpuStringProperty(1, fmt.Sprintf(cStateDisableFileFmt, 3))
+	assert.Equal(t, "1", value)
+}
+
+func TestNewCStatesFromLatencyUnknownLatency(t *testing.T) {
+	defer setupCpuCStatesTests(map[string]map[string]map[string]string{
+		"cpu0": {
+			"state0": {"name": "POLL", "latency": "0", "residency": "0"},
+			"state1": {"name": "C6", "residency": "600"},
+		},
+	})()
+	assert.NoError(t, mapAvailableCStates())
+
+	// state with unknown latency is not treated as the shallowest one
+	_, err := NewCStatesFromLatency(20)
+	assert.ErrorContains(t, err, "could not read exit latency of C-State C6")
+	_, err = NewCStatesFromResidency(1000)
+	assert.Error(t, err)
+}
+
+func TestNewCStatesFromLatency(t *testing.T) {
+	cStatesInfoMap = map[string]CStateInfo{
+		"POLL": {Name: "POLL", Number: 0},
+		"C1":   {Name: "C1", Number: 1, Latency: 2, Residency: 2},
+		"C1E":  {Name: "C1E", Number: 2, Latency: 10, Residency: 20},
+		"C6":   {Name: "C6", Number: 3, Latency: 170, Residency: 600},
+	}
+	_, err := NewCStatesFromLatency(20)
+	assert.ErrorIs(t, err, uninitialisedErr)
+
+	defer setupCpuCStatesTests(nil)()
+	states, err := NewCStatesFromLatency(20)
+	assert.NoError(t, err)
+	assert.Equal(t, CStates{"POLL": true, "C1": true, "C1E": true, "C6": false}, states)
+
+	states, err = NewCStatesFromLatency(0)
+	assert.NoError(t, err)
+	assert.Equal(t, CStates{"POLL": true, "C1": false, "C1E": false, "C6": false}, states)
+
+	states, err = NewCStatesFromResidency(600)
+	assert.NoError(t, err)
+	assert.Equal(t, CStates{"POLL": true, "C1": true, "C1E": true, "C6": true}, states)
+
+	states, err = NewCStatesFromResidency(10)
+	assert.NoError(t, err)
+	assert.Equal(t, CStates{"POLL": true, "C1": true, "C1E": false, "C6": false}, states)
+}
+
 func TestCStates_preCheckCStates(t *testing.T) {
 	teardown := setupCpuCStatesTests(map[string]map[string]map[string]string{
 		"cpu0":   nil,
@@ -199,16 +376,25 @@ func TestHostImpl_AvailableCStates(t *testing.T) {
 	assert.Empty(t, host.AvailableCStates())
 	defer setupCpuCStatesTests(nil)()
 
-	assert.ElementsMatch(t, host.AvailableCStates(), []string{"C1", "C2", "C3"})
+	assert.Equal(t, []CStateInfo{
+		{Name: "C1", Number: 1},
+		{Name: "C2", Number: 2},
+		{Name: "C3", Number: 3},
+	}, host.AvailableCStates())
+
+	cStatesInfoMap["C2"] = CStateInfo{Name: "C2", Number: 2, Latency: 10}
+	assert.Equal(t, uint(10), host.AvailableCStates()[1].Latency)
 }
 
 func TestPoolImpl_SetCStates(t *testing.T) {
 	core1 := new(cpuMock)
 	core1.On("consolidate").Return(nil)
+	core1.On("GetID").Return(uint(0))
 
 	core2 := new(cpuMock)
 	pool := &poolImpl{
-		cpus: CpuList{core1},
+		cpus:  CpuList{core1},
+		mutex: &sync.Mutex{},
 	}
 	// cstates not supported
 	assert.ErrorIs(t, pool.SetCStates(nil), uninitialisedErr)
@@ -228,6 +414,7 @@ func TestPoolImpl_SetCStates(t *testing.T) {
 	core1 = new(cpuMock)
 	pool.cpus = CpuList{core1}
 	core1.On("consolidate").Return(fmt.Errorf("consolidate failed"))
+	core1.On("GetID").Return(uint(0))
 	assert.ErrorContains(t, pool.SetCStates(CStates{"C0": true}), "failed to apply c-states: consolidate failed")
 }
 
@@ -282,9 +469,11 @@ func TestCpuImpl_updateCStates(t *testing.T) {
 func TestCpuImpl_SetCStates(t *testing.T) {
 	pool := new(poolMock)
 	pool.On("getCStates").Return(nil)
+	pool.On("poolMutex").Return(&sync.Mutex{})
 	core := &cpuImpl{
-		id:   0,
-		pool: pool,
+		id:    0,
+		pool:  pool,
+		mutex: &sync.Mutex{},
 	}
 	assert.ErrorIs(t, core.SetCStates(nil), uninitialisedErr)
 	defer setupCpuCStatesTests(map[string]map[string]map[string]string{
@@ -1,6 +1,8 @@
 package power
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strings"
@@ -32,15 +34,23 @@ var CpuTypeReferences = supportedCores{}
 type Cpu interface {
 	GetID() uint
 	GetAbsMinMax() (uint, uint)
+	// guaranteed base frequency in kHz, 0 if not reported by the driver
+	GetBaseFrequency() uint
 	SetPool(pool Pool) error
 
 	getPool() Pool
 	doSetPool(pool Pool) error
+	moveLocked(from, to Pool) error
 	consolidate() error
 	consolidate_unsafe() error
 	GetCore() Core
+	// per CPU power profile, takes precedence over the profile of the pool
+	SetPowerProfile(profile Profile) error
+	ClearPowerProfile() error
+	getPowerProfile() Profile
 	// C-States stuff
-	SetCStates(cStates CStates) error
+	SetCStates(cStates CStates, opts ...CStatesOption) error
+	getCStates() *CStates
 
 	// used only to set initial pool when creating core instance
 	_setPoolProperty(pool Pool)
@@ -51,6 +61,13 @@ type cpuImpl struct {
 	mutex sync.Locker
 	pool  Pool
 	core  Core
+	// Scaling-Driver properties
+	powerProfile Profile
+	baseFreq     uint
+	// EPB at initialisation, restored when the profile in effect sets none
+	initEpb string
+	// governor tunable files written for the cpu, restored to initial values when the profile stops setting them
+	tunableFiles []string
 	// C-States properties
 	cStates *CStates
 }
@@ -69,6 +86,20 @@ func newCpu(coreID uint, core Core) (Cpu, error) {
 		mutex: &sync.Mutex{},
 		core:  core,
 	}
+	if featureList.isFeatureIdSupported(FrequencyScalingFeature) {
+		// not reported by all drivers
+		if base, err := readCpuUintProperty(coreID, baseFreqFile); err == nil {
+			cpu.baseFreq = base
+		}
+	}
+	if featureList.isFeatureIdSupported(EPBFeature) {
+		// without the initial value epb of the cpu is left as set by the last profile
+		if epb, err := readCpuStringProperty(coreID, epbFile); err == nil {
+			cpu.initEpb = epb
+		} else {
+			log.Info("could not read initial EPB", "cpu", coreID, "error", err)
+		}
+	}
 
 	return cpu, nil
 }
@@ -85,9 +116,64 @@ func (cpu *cpuImpl) consolidate_unsafe() error {
 	if err := cpu.updateCStates(); err != nil {
 		return err
 	}
+	if err := cpu.updateLatencyLimit(); err != nil {
+		return err
+	}
 	return nil
 }
 
+// number of cpus consolidated concurrently, set by LibConfig
+var consolidationWorkers uint = 1
+
+// consolidateCpus consolidates every cpu of the list using up to consolidationWorkers goroutines. all cpus are
+// consolidated even if some fail, errors are joined in the order of the list
+func consolidateCpus(cpus CpuList) error {
+	_, err := consolidateCpusContext(context.Background(), cpus)
+	return err
+}
+
+// consolidateCpusContext stops starting consolidation of further cpus once ctx is done and returns cpus that
+// were consolidated, in the order of the list. ctx error is not part of the returned error
+func consolidateCpusContext(ctx context.Context, cpus CpuList) (CpuList, error) {
+	workers := max(consolidationWorkers, 1)
+	cpuErrs := make([]error, len(cpus))
+	started := make([]bool, len(cpus))
+	slots := make(chan struct{}, workers)
+	wg := sync.WaitGroup{}
+	for i, cpu := range cpus {
+		if ctx.Err() != nil {
+			break
+		}
+		if workers == 1 {
+			started[i] = true
+			cpuErrs[i] = cpu.consolidate()
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			continue
+		case slots <- struct{}{}:
+		}
+		started[i] = true
+		wg.Add(1)
+		go func(i int, cpu Cpu) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			cpuErrs[i] = cpu.consolidate()
+		}(i, cpu)
+	}
+	wg.Wait()
+	consolidated := make(CpuList, 0, len(cpus))
+	for i, cpu := range cpus {
+		if started[i] {
+			consolidated = append(consolidated, cpu)
+		}
+	}
+	return consolidated, errors.Join(cpuErrs...)
+}
+
 // SetPool moves current core to a specified target pool
 // allowed movements are reservedPoolType <-> sharedPoolType and sharedPoolType <-> any exclusive pool
 func (cpu *cpuImpl) SetPool(targetPool Pool) error {
@@ -111,9 +197,9 @@ func (cpu *cpuImpl) SetPool(targetPool Pool) error {
 		return fmt.Errorf("target pool cannot be nil")
 	}
 
+	unlock := cpu.lockWithPools(targetPool)
+	defer unlock()
 	log.Info("Set pool", "cpu", cpu.id, "source pool", cpu.pool.Name(), "target pool", targetPool.Name())
-	cpu.mutex.Lock()
-	defer cpu.mutex.Unlock()
 
 	if cpu.pool == targetPool { // case 0,1,5
 		return nil
@@ -121,15 +207,15 @@ func (cpu *cpuImpl) SetPool(targetPool Pool) error {
 	reservedPool := cpu.pool.getHost().GetReservedPool()
 	sharedPool := cpu.pool.getHost().GetSharedPool()
 	if cpu.pool == reservedPool && targetPool.isExclusive() { // case 3
-		return fmt.Errorf("cannot move from reserved to exclusive pool")
+		return &PoolTransitionError{CpuID: cpu.id, From: cpu.pool.Name(), To: targetPool.Name(), Reason: "cannot move from reserved to exclusive pool"}
 	}
 
 	if cpu.pool.isExclusive() && targetPool.isExclusive() { // case 7
-		return fmt.Errorf("cannot move exclusive to different exclusive pool")
+		return &PoolTransitionError{CpuID: cpu.id, From: cpu.pool.Name(), To: targetPool.Name(), Reason: "cannot move exclusive to different exclusive pool"}
 	}
 
 	if cpu.pool.isExclusive() && targetPool == reservedPool { // case 9
-		return fmt.Errorf("cannot move from exclusive to reserved")
+		return &PoolTransitionError{CpuID: cpu.id, From: cpu.pool.Name(), To: targetPool.Name(), Reason: "cannot move from exclusive to reserved"}
 	}
 
 	// cases 2,4,5,6,8
@@ -139,25 +225,16 @@ func (cpu *cpuImpl) SetPool(targetPool Pool) error {
 	panic("we should never get here")
 }
 
+// doSetPool moves the cpu, caller holds mutexes of the cpu and both pools
 func (cpu *cpuImpl) doSetPool(pool Pool) error {
-	cpu.pool.poolMutex().Lock()
-	pool.poolMutex().Lock()
-	log.V(4).Info("acquired mutexes", "source", cpu.pool.Name(), "target", pool.Name(), "cpu", cpu.id)
-
 	origPool := cpu.pool
 	cpu.pool = pool
 
-	defer func() {
-		log.V(4).Info("releasing mutexes", "source", origPool.Name(), "target", pool.Name())
-		origPool.poolMutex().Unlock()
-		pool.poolMutex().Unlock()
-	}()
-
 	origPoolCpus := origPool.Cpus()
 	log.V(4).Info("removing cpu from pool", "pool", origPool.Name(), "coreID", cpu.id)
 	if err := origPoolCpus.remove(cpu); err != nil {
 		cpu.pool = origPool
-		return err
+		return &CpuNotFoundError{CpuID: cpu.id, Pool: origPool.Name()}
 	}
 
 	log.V(4).Info("starting consolidation of cpu", "coreID", cpu.id)
@@ -169,10 +246,32 @@ func (cpu *cpuImpl) doSetPool(pool Pool) error {
 
 	newPoolCpus := cpu.pool.Cpus()
 	newPoolCpus.add(cpu)
+
+	// pools requesting uncore need to re-evaluate which dies they cover
+	for _, p := range []Pool{origPool, pool} {
+		if p.GetUncore() == nil {
+			continue
+		}
+		if err := p.reconcileUncore(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// moveLocked moves the cpu if it is still in pool from, caller holds mutexes of both pools
+func (cpu *cpuImpl) moveLocked(from, to Pool) error {
+	cpu.mutex.Lock()
+	defer cpu.mutex.Unlock()
+	if cpu.pool != from {
+		return &CpuNotFoundError{CpuID: cpu.id, Pool: from.Name()}
+	}
+	return cpu.doSetPool(to)
+}
+
 func (cpu *cpuImpl) getPool() Pool {
+	cpu.mutex.Lock()
+	defer cpu.mutex.Unlock()
 	return cpu.pool
 }
 
@@ -251,7 +350,7 @@ func (cpus *CpuList) add(cpu Cpu) {
 func (cpus *CpuList) remove(cpu Cpu) error {
 	index := cpus.IndexOf(cpu)
 	if index < 0 {
-		return fmt.Errorf("cpu %d is not in pool", cpu.GetID())
+		return &CpuNotFoundError{CpuID: cpu.GetID()}
 	}
 	size := len(*cpus) - 1
 	(*cpus)[index] = (*cpus)[size]
@@ -285,7 +384,7 @@ func (cpus *CpuList) ManyByIDs(ids []uint) (CpuList, error) {
 	for i, id := range ids {
 		cpu := cpus.ByID(id)
 		if cpu == nil {
-			return nil, fmt.Errorf("cpu with id %d, not in list", id)
+			return nil, &CpuNotFoundError{CpuID: id}
 		}
 		targets[i] = cpu
 	}
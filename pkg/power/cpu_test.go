@@ -7,7 +7,9 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -17,10 +19,34 @@ type cpuMock struct {
 	mock.Mock
 }
 
-func (m *cpuMock) SetCStates(cStates CStates) error {
+func (m *cpuMock) SetCStates(cStates CStates, opts ...CStatesOption) error {
 	return m.Called(cStates).Error(0)
 }
 
+func (m *cpuMock) getCStates() *CStates {
+	args := m.Called().Get(0)
+	if args == nil {
+		return nil
+	}
+	return args.(*CStates)
+}
+
+func (m *cpuMock) SetPowerProfile(profile Profile) error {
+	return m.Called(profile).Error(0)
+}
+
+func (m *cpuMock) ClearPowerProfile() error {
+	return m.Called().Error(0)
+}
+
+func (m *cpuMock) getPowerProfile() Profile {
+	args := m.Called().Get(0)
+	if args == nil {
+		return nil
+	}
+	return args.(Profile)
+}
+
 func (m *cpuMock) _setPoolProperty(pool Pool) {
 	m.Called(pool)
 }
@@ -33,6 +59,9 @@ func (m *cpuMock) consolidate_unsafe() error {
 func (m *cpuMock) doSetPool(pool Pool) error {
 	return m.Called(pool).Error(0)
 }
+func (m *cpuMock) moveLocked(from, to Pool) error {
+	return m.Called(from, to).Error(0)
+}
 func (m *cpuMock) GetID() uint {
 	args := m.Called()
 	return args.Get(0).(uint)
@@ -42,6 +71,10 @@ func (m *cpuMock) GetAbsMinMax() (uint, uint) {
 	return args.Get(0).(uint), args.Get(1).(uint)
 }
 
+func (m *cpuMock) GetBaseFrequency() uint {
+	return m.Called().Get(0).(uint)
+}
+
 func (m *cpuMock) getPool() Pool {
 	args := m.Called().Get(0)
 	if args == nil {
@@ -73,6 +106,9 @@ func (m *mutexMock) Unlock() {
 func setupCpuScalingTests(cpufiles map[string]map[string]string) func() {
 	origBasePath := basePath
 	basePath = "testing/cpus"
+	// fake files are recreated, cached values of previous tests do not apply
+	sysfsWrites.reset(false)
+	sharedTunables.reset()
 	defaultDefaultPowerProfile := defaultPowerProfile
 	typeCopy := coreTypes
 	referenceCopy := CpuTypeReferences
@@ -195,6 +231,7 @@ func TestCpuImpl_SetPool(t *testing.T) {
 	sharedPoolCores := make(CpuList, 8)
 	sharedPool.On("Cpus").Return(&sharedPoolCores)
 	sharedPool.On("poolMutex").Return(&sync.Mutex{})
+	sharedPool.On("GetUncore").Return(nil)
 
 	reservedPool := new(poolMock)
 	reservedPool.On("isExclusive").Return(false)
@@ -203,6 +240,7 @@ func TestCpuImpl_SetPool(t *testing.T) {
 	reservedPoolCores := make(CpuList, 8)
 	reservedPool.On("Cpus").Return(&reservedPoolCores)
 	reservedPool.On("poolMutex").Return(&sync.Mutex{})
+	reservedPool.On("GetUncore").Return(nil)
 
 	host.On("GetReservedPool").Return(reservedPool)
 	host.On("GetSharedPool").Return(sharedPool)
@@ -214,6 +252,7 @@ func TestCpuImpl_SetPool(t *testing.T) {
 	exclusivePool1Cores := make(CpuList, 8)
 	exclusivePool1.On("Cpus").Return(&exclusivePool1Cores)
 	exclusivePool1.On("poolMutex").Return(&sync.Mutex{})
+	exclusivePool1.On("GetUncore").Return(nil)
 
 	exclusivePool2 := new(poolMock)
 	exclusivePool2.On("isExclusive").Return(true)
@@ -222,6 +261,7 @@ func TestCpuImpl_SetPool(t *testing.T) {
 	exclusivePool2Cores := make(CpuList, 8)
 	exclusivePool2.On("Cpus").Return(&exclusivePool2Cores)
 	exclusivePool2.On("poolMutex").Return(&sync.Mutex{})
+	exclusivePool2.On("GetUncore").Return(nil)
 
 	cpu := &cpuImpl{
 		id:   0,
@@ -366,27 +406,15 @@ func TestCpuImpl_SetPool(t *testing.T) {
 
 func TestCpuImpl_doSetPool(t *testing.T) {
 	var sourcePool, targetPool *poolMock
-	var sourcePoolMutex, targetPoolMutex *mutexMock
-
 	var cpu *cpuImpl
-	// happy path
+	// happy path, mutexes are held by the caller
 	sourcePool = new(poolMock)
 	sourcePool.On("Name").Return("sauce")
-	sourcePoolMutex = new(mutexMock)
-
-	sourcePoolMutex.On("Unlock").Return().NotBefore(
-		sourcePoolMutex.On("Lock").Return(),
-	)
-	sourcePool.On("poolMutex").Return(sourcePoolMutex)
+	sourcePool.On("GetUncore").Return(nil)
 
 	targetPool = new(poolMock)
 	targetPool.On("Name").Return("target")
-	targetPoolMutex = new(mutexMock)
-
-	targetPoolMutex.On("Unlock").Return().NotBefore(
-		targetPoolMutex.On("Lock").Return(),
-	)
-	targetPool.On("poolMutex").Return(targetPoolMutex)
+	targetPool.On("GetUncore").Return(nil)
 
 	cpu = &cpuImpl{
 		pool: sourcePool,
@@ -396,24 +424,17 @@ func TestCpuImpl_doSetPool(t *testing.T) {
 
 	assert.NoError(t, cpu.doSetPool(targetPool))
 	assert.True(t, cpu.pool == targetPool)
-	sourcePoolMutex.AssertExpectations(t)
-	targetPoolMutex.AssertExpectations(t)
+	sourcePool.AssertNotCalled(t, "poolMutex")
+	targetPool.AssertNotCalled(t, "poolMutex")
 
 	// remove failure
 	sourcePool = new(poolMock)
 	sourcePool.On("Name").Return("sauce")
-	sourcePoolMutex.On("Unlock").Return().NotBefore(
-		sourcePoolMutex.On("Lock").Return(),
-	)
-	sourcePool.On("poolMutex").Return(sourcePoolMutex)
+	sourcePool.On("GetUncore").Return(nil)
 
 	targetPool = new(poolMock)
 	targetPool.On("Name").Return("target")
-	targetPoolMutex = new(mutexMock)
-	targetPoolMutex.On("Unlock").Return().NotBefore(
-		targetPoolMutex.On("Lock").Return(),
-	)
-	targetPool.On("poolMutex").Return(targetPoolMutex)
+	targetPool.On("GetUncore").Return(nil)
 
 	cpu = &cpuImpl{
 		pool: sourcePool,
@@ -423,8 +444,6 @@ func TestCpuImpl_doSetPool(t *testing.T) {
 
 	assert.ErrorContains(t, cpu.doSetPool(targetPool), "not in pool")
 	assert.True(t, cpu.pool == sourcePool)
-	sourcePoolMutex.AssertExpectations(t)
-	targetPoolMutex.AssertExpectations(t)
 }
 
 func TestCoreList_IDs(t *testing.T) {
@@ -481,3 +500,47 @@ func TestCoreList_ManyByIDs(t *testing.T) {
 	returnedList, err = cpus.ManyByIDs([]uint{6})
 	assert.Error(t, err)
 }
+
+func TestConsolidateCpus(t *testing.T) {
+	defer func(workers uint) { consolidationWorkers = workers }(consolidationWorkers)
+
+	var running, maxRunning atomic.Int32
+	newCpus := func() CpuList {
+		cpus := make(CpuList, 16)
+		for i := range cpus {
+			cpu := new(cpuMock)
+			var err error
+			if i%5 == 1 {
+				err = fmt.Errorf("cpu %d failed", i)
+			}
+			cpu.On("consolidate").Return(err).Run(func(args mock.Arguments) {
+				current := running.Add(1)
+				for {
+					highest := maxRunning.Load()
+					if current <= highest || maxRunning.CompareAndSwap(highest, current) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+				running.Add(-1)
+			})
+			cpus[i] = cpu
+		}
+		return cpus
+	}
+	expectedErr := "cpu 1 failed\ncpu 6 failed\ncpu 11 failed"
+
+	for _, workers := range []uint{0, 1, 4} {
+		consolidationWorkers = workers
+		maxRunning.Store(0)
+		cpus := newCpus()
+		err := consolidateCpus(cpus)
+		// every cpu is consolidated despite failures, errors keep the order of the list
+		assert.EqualError(t, err, expectedErr, workers)
+		for _, cpu := range cpus {
+			cpu.(*cpuMock).AssertExpectations(t)
+		}
+		assert.LessOrEqual(t, maxRunning.Load(), int32(max(workers, 1)), workers)
+	}
+	assert.NoError(t, consolidateCpus(CpuList{}))
+}
@@ -0,0 +1,78 @@
+package power
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+)
+
+const epbFile = "power/energy_perf_bias"
+
+// named energy performance bias values accepted by the kernel
+var epbPresetValues = map[string]uint{
+	"performance":         0,
+	"balance-performance": 4,
+	"normal":              6,
+	"balance-power":       8,
+	"power":               15,
+}
+
+func initEpb() featureStatus {
+	feature := featureStatus{
+		name:     "Energy-Performance-Bias",
+		initFunc: initEpb,
+		details:  epbDetails,
+	}
+	if _, err := os.Stat(filepath.Join(basePath, "cpu0", epbFile)); err != nil {
+		feature.err = fmt.Errorf("EPB file %s does not exist", epbFile)
+	}
+	return feature
+}
+
+func epbDetails() featureDetails {
+	presets := make([]string, 0, len(epbPresetValues))
+	for preset := range epbPresetValues {
+		presets = append(presets, preset)
+	}
+	sort.Slice(presets, func(i, j int) bool { return epbPresetValues[presets[i]] < epbPresetValues[presets[j]] })
+	return featureDetails{
+		paths:        []string{allCpusPath(epbFile)},
+		capabilities: map[string]any{"presets": presets},
+	}
+}
+
+// WithEpb sets energy performance bias applied to cpus of the profile, either a value 0-15
+// or one of performance, balance-performance, normal, balance-power, power
+func WithEpb(epb string) ProfileOption {
+	return func(profile *profileImpl) error {
+		if !IsFeatureSupported(EPBFeature) {
+			return featureList.getFeatureIdError(EPBFeature)
+		}
+		value, err := parseEpb(epb)
+		if err != nil {
+			return err
+		}
+		profile.epb = fmt.Sprint(value)
+		return nil
+	}
+}
+
+func parseEpb(epb string) (uint, error) {
+	if value, exists := epbPresetValues[epb]; exists {
+		return value, nil
+	}
+	value, err := strconv.ParseUint(epb, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("epb has to be within 0-15 or one of performance, balance-performance, normal, balance-power, power, got %s", epb)
+	}
+	if value > 15 {
+		return 0, &OutOfRangeError{Setting: "epb", Value: uint(value), Min: 0, Max: 15}
+	}
+	return uint(value), nil
+}
+
+func (cpu *cpuImpl) writeEpbValue(epb string) error {
+	return writeSysfsValue(filepath.Join(basePath, fmt.Sprint("cpu", cpu.id), epbFile), epb)
+}
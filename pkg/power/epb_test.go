@@ -0,0 +1,119 @@
+package power
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_initEpb(t *testing.T) {
+	defer setupCpuScalingTests(map[string]map[string]string{"cpu0": {}})()
+	feature := initEpb()
+	assert.Equal(t, "Energy-Performance-Bias", feature.name)
+	assert.ErrorContains(t, feature.err, "does not exist")
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(basePath, "cpu0", "power"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(basePath, "cpu0", epbFile), []byte("6\n"), 0644))
+	assert.NoError(t, initEpb().err)
+}
+
+func TestWithEpb(t *testing.T) {
+	oldGovs := availableGovs
+	availableGovs = []string{cpuPolicyPowersave}
+	defer func() { availableGovs = oldGovs }()
+	defer setupCpuScalingTests(map[string]map[string]string{"cpu0": {}})()
+
+	_, err := NewPowerProfile("name", 0, 100, cpuPolicyPowersave, "", WithEpb("normal"))
+	assert.ErrorIs(t, err, uninitialisedErr)
+
+	featureList[EPBFeature].err = nil
+	defer func() { featureList[EPBFeature].err = uninitialisedErr }()
+	profile, err := NewPowerProfile("name", 0, 100, cpuPolicyPowersave, "", WithEpb("balance-power"))
+	assert.NoError(t, err)
+	assert.Equal(t, "8", profile.Epb())
+
+	profile, err = NewPowerProfile("name", 0, 100, cpuPolicyPowersave, "", WithEpb("15"))
+	assert.NoError(t, err)
+	assert.Equal(t, "15", profile.Epb())
+
+	for _, invalid := range []string{"-1", "balance_power"} {
+		_, err = NewPowerProfile("name", 0, 100, cpuPolicyPowersave, "", WithEpb(invalid))
+		assert.ErrorContains(t, err, "epb has to be within 0-15")
+	}
+	_, err = NewPowerProfile("name", 0, 100, cpuPolicyPowersave, "", WithEpb("16"))
+	var rangeErr *OutOfRangeError
+	assert.ErrorAs(t, err, &rangeErr)
+	assert.Equal(t, OutOfRangeError{Setting: "epb", Value: 16, Min: 0, Max: 15}, *rangeErr)
+
+	profile, err = NewPowerProfile("name", 0, 100, cpuPolicyPowersave, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "", profile.Epb())
+}
+
+func TestCpuImpl_setDriverValuesEpb(t *testing.T) {
+	defer setupCpuScalingTests(map[string]map[string]string{"cpu0": {"max": "9999", "min": "999"}})()
+	coreTypes = CoreTypeList{&CpuFrequencySet{min: 999, max: 9999}}
+	assert.NoError(t, os.MkdirAll(filepath.Join(basePath, "cpu0", "power"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(basePath, "cpu0", epbFile), []byte("6"), 0644))
+	cpu := &cpuImpl{id: 0, core: &cpuCore{}}
+	profile := &profileImpl{governor: cpuPolicyPowersave, min: 999, max: 9999, epb: "0"}
+
+	// not written if feature is not supported
+	assert.NoError(t, cpu.setDriverValues(profile))
+	value, _ := readCpuUintProperty(0, epbFile)
+	assert.Equal(t, uint(6), value)
+
+	featureList[EPBFeature].err = nil
+	defer func() { featureList[EPBFeature].err = uninitialisedErr }()
+	assert.NoError(t, cpu.setDriverValues(profile))
+	value, _ = readCpuUintProperty(0, epbFile)
+	assert.Equal(t, uint(0), value)
+
+	assert.NoError(t, os.Remove(filepath.Join(basePath, "cpu0", epbFile)))
+	assert.NoError(t, os.Remove(filepath.Join(basePath, "cpu0", "power")))
+	// value applied already would not be written again
+	sysfsWrites.reset(false)
+	assert.ErrorContains(t, cpu.setDriverValues(profile), "failed to set EPB value for cpu 0")
+}
+
+func TestCpuImpl_restoresInitialEpb(t *testing.T) {
+	defer setupCpuScalingTests(map[string]map[string]string{
+		"cpu0": {"max": "9999", "min": "999", "governor": cpuPolicyPowersave},
+	})()
+	coreTypes = CoreTypeList{}
+	assert.NoError(t, os.MkdirAll(filepath.Join(basePath, "cpu0", "power"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(basePath, "cpu0", epbFile), []byte("6\n"), 0644))
+	featureList[EPBFeature].err = nil
+	defer func() { featureList[EPBFeature].err = uninitialisedErr }()
+
+	cpu, err := newCpu(0, &cpuCore{})
+	assert.NoError(t, err)
+	assert.Equal(t, "6", cpu.(*cpuImpl).initEpb)
+
+	host := &hostImpl{}
+	host.reservedPool = &reservedPoolType{poolImpl{name: reservedPoolName, mutex: &sync.Mutex{}, host: host}}
+	host.sharedPool = &sharedPoolType{poolImpl{name: sharedPoolName, mutex: &sync.Mutex{}, host: host}}
+	profile := &profileImpl{name: "epb", governor: cpuPolicyPowersave, min: 999, max: 9999, epb: "0"}
+	pool := &exclusivePoolType{poolImpl{name: "epb", mutex: &sync.Mutex{}, host: host, PowerProfile: profile}}
+	cpu._setPoolProperty(host.sharedPool)
+	host.sharedPool.Cpus().add(cpu)
+
+	// moving out of a pool with epb restores the initial value
+	assert.NoError(t, cpu.SetPool(pool))
+	value, _ := readCpuUintProperty(0, epbFile)
+	assert.Equal(t, uint(0), value)
+	assert.NoError(t, cpu.SetPool(host.sharedPool))
+	value, _ = readCpuUintProperty(0, epbFile)
+	assert.Equal(t, uint(6), value)
+
+	// as does clearing a cpu profile with epb
+	assert.NoError(t, cpu.SetPowerProfile(profile))
+	value, _ = readCpuUintProperty(0, epbFile)
+	assert.Equal(t, uint(0), value)
+	assert.NoError(t, cpu.ClearPowerProfile())
+	value, _ = readCpuUintProperty(0, epbFile)
+	assert.Equal(t, uint(6), value)
+}
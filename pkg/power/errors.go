@@ -0,0 +1,191 @@
+package power
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sentinel errors, returned errors match them with errors.Is
+var (
+	// operation needs a feature that is not supported on the system, returned errors are FeatureError
+	ErrFeatureUnsupported = errors.New("feature not supported")
+	// feature was not initialised by creating an instance of the library
+	ErrFeatureUninitialized = errors.New("feature uninitialized")
+	// feature is not known to the library
+	ErrFeatureUndefined = errors.New("feature undefined")
+	// cpu cannot be moved between the requested pools, returned errors are PoolTransitionError
+	ErrInvalidPoolTransition = errors.New("invalid pool transition")
+	// cpu is not in the list or pool, returned errors are CpuNotFoundError
+	ErrCpuNotFound = errors.New("cpu not found")
+	// c-state does not exist on the system or some cpus, returned errors are CStateNotFoundError
+	ErrCStateNotFound = errors.New("c-state not found")
+	// value is outside of the range accepted by hardware or the library, returned errors are OutOfRangeError
+	ErrOutOfRange = errors.New("value out of range")
+	// writing to sysfs failed, returned errors are SysfsWriteError
+	ErrSysfsWrite = errors.New("sysfs write failed")
+	// value or setting is not available on the system, NotAvailableError lists the available values
+	ErrNotAvailable = errors.New("not available")
+	// pool with the name does not exist, returned errors are PoolNotFoundError
+	ErrPoolNotFound = errors.New("pool not found")
+	// shared pool has no cpus satisfying an allocation request
+	ErrNotEnoughCpus = errors.New("not enough cpus")
+)
+
+var (
+	uninitialisedErr = ErrFeatureUninitialized
+	undefinederr     = ErrFeatureUndefined
+)
+
+// FeatureError is returned by operations using a feature that is not supported, Err describes the reason
+type FeatureError struct {
+	Feature FeatureID
+	Name    string
+	Err     error
+}
+
+func (e *FeatureError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *FeatureError) Unwrap() error {
+	return e.Err
+}
+
+func (e *FeatureError) Is(target error) bool {
+	return target == ErrFeatureUnsupported
+}
+
+// PoolTransitionError is returned when a cpu cannot be moved from its pool to the target pool
+type PoolTransitionError struct {
+	CpuID  uint
+	From   string
+	To     string
+	Reason string
+}
+
+func (e *PoolTransitionError) Error() string {
+	return e.Reason
+}
+
+func (e *PoolTransitionError) Is(target error) bool {
+	return target == ErrInvalidPoolTransition
+}
+
+// CpuNotFoundError is returned when a cpu is not in a list, or in a pool if Pool is set
+type CpuNotFoundError struct {
+	CpuID uint
+	Pool  string
+}
+
+func (e *CpuNotFoundError) Error() string {
+	if e.Pool == "" {
+		return fmt.Sprintf("cpu with id %d, not in list", e.CpuID)
+	}
+	return fmt.Sprintf("cpu %d is not in pool %s", e.CpuID, e.Pool)
+}
+
+func (e *CpuNotFoundError) Is(target error) bool {
+	return target == ErrCpuNotFound
+}
+
+// CStateNotFoundError is returned for c-states missing on the system, or on Cpus if set
+type CStateNotFoundError struct {
+	Name string
+	Cpus []uint
+}
+
+func (e *CStateNotFoundError) Error() string {
+	if len(e.Cpus) == 0 {
+		return fmt.Sprintf("c-state %s does not exist on this system", e.Name)
+	}
+	return fmt.Sprintf("c-state %s is not available on cpus %v", e.Name, e.Cpus)
+}
+
+func (e *CStateNotFoundError) Is(target error) bool {
+	return target == ErrCStateNotFound
+}
+
+// PoolNotFoundError is returned when an exclusive pool with Name does not exist
+type PoolNotFoundError struct {
+	Name string
+}
+
+func (e *PoolNotFoundError) Error() string {
+	return fmt.Sprintf("exclusive pool %s does not exist", e.Name)
+}
+
+func (e *PoolNotFoundError) Is(target error) bool {
+	return target == ErrPoolNotFound
+}
+
+// NotAvailableError is returned when Value of a setting is not one of the Available values
+type NotAvailableError struct {
+	Setting   string
+	Value     string
+	Available []string
+}
+
+func (e *NotAvailableError) Error() string {
+	return fmt.Sprintf("%s %s is not available, available values: %v", e.Setting, e.Value, e.Available)
+}
+
+func (e *NotAvailableError) Is(target error) bool {
+	return target == ErrNotAvailable
+}
+
+// OutOfRangeError is returned for values outside of Min-Max range
+type OutOfRangeError struct {
+	Setting string
+	Value   uint
+	Min     uint
+	Max     uint
+}
+
+func (e *OutOfRangeError) Error() string {
+	return fmt.Sprintf("%s %d is out of range %d-%d", e.Setting, e.Value, e.Min, e.Max)
+}
+
+func (e *OutOfRangeError) Is(target error) bool {
+	return target == ErrOutOfRange
+}
+
+// SysfsWriteError is returned when writing Value to a sysfs file fails. Cpu is -1 for files not specific to a cpu
+type SysfsWriteError struct {
+	Path  string
+	Cpu   int
+	Value string
+	Err   error
+}
+
+func newSysfsWriteError(filePath string, value string, err error) *SysfsWriteError {
+	return &SysfsWriteError{Path: filePath, Cpu: cpuOfPath(filePath), Value: value, Err: err}
+}
+
+func (e *SysfsWriteError) Error() string {
+	return fmt.Sprintf("failed to write %q to %s: %v", e.Value, e.Path, e.Err)
+}
+
+func (e *SysfsWriteError) Unwrap() error {
+	return e.Err
+}
+
+func (e *SysfsWriteError) Is(target error) bool {
+	return target == ErrSysfsWrite
+}
+
+// returns id of the cpu directory a path is in, -1 if it's not in one
+func cpuOfPath(filePath string) int {
+	for _, element := range strings.Split(filepath.ToSlash(filePath), "/") {
+		id, found := strings.CutPrefix(element, "cpu")
+		if !found {
+			continue
+		}
+		if value, err := strconv.Atoi(id); err == nil && value >= 0 {
+			return value
+		}
+	}
+	return -1
+}
@@ -0,0 +1,71 @@
+package power
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedErrors(t *testing.T) {
+	var err error
+
+	err = fmt.Errorf("wrapped: %w", &FeatureError{Feature: EPPFeature, Name: "EPP", Err: uninitialisedErr})
+	assert.ErrorIs(t, err, ErrFeatureUnsupported)
+	assert.ErrorIs(t, err, ErrFeatureUninitialized)
+	var featureErr *FeatureError
+	assert.ErrorAs(t, err, &featureErr)
+	assert.Equal(t, EPPFeature, featureErr.Feature)
+
+	err = fmt.Errorf("wrapped: %w", &PoolTransitionError{CpuID: 1, From: "a", To: "b", Reason: "reason"})
+	assert.ErrorIs(t, err, ErrInvalidPoolTransition)
+	assert.NotErrorIs(t, err, ErrCpuNotFound)
+	assert.ErrorContains(t, err, "reason")
+
+	err = &CpuNotFoundError{CpuID: 3}
+	assert.ErrorIs(t, err, ErrCpuNotFound)
+	assert.EqualError(t, err, "cpu with id 3, not in list")
+	assert.EqualError(t, &CpuNotFoundError{CpuID: 3, Pool: "pool"}, "cpu 3 is not in pool pool")
+
+	err = &CStateNotFoundError{Name: "C6"}
+	assert.ErrorIs(t, err, ErrCStateNotFound)
+	assert.EqualError(t, err, "c-state C6 does not exist on this system")
+	assert.EqualError(t, &CStateNotFoundError{Name: "C6", Cpus: []uint{1, 2}}, "c-state C6 is not available on cpus [1 2]")
+
+	err = &OutOfRangeError{Setting: "epb", Value: 16, Min: 0, Max: 15}
+	assert.ErrorIs(t, err, ErrOutOfRange)
+	assert.EqualError(t, err, "epb 16 is out of range 0-15")
+
+	err = fmt.Errorf("wrapped: %w", &PoolNotFoundError{Name: "pool"})
+	assert.ErrorIs(t, err, ErrPoolNotFound)
+	assert.ErrorContains(t, err, "exclusive pool pool does not exist")
+
+	err = &NotAvailableError{Setting: "governor", Value: "ondemand", Available: []string{"powersave", "performance"}}
+	assert.ErrorIs(t, err, ErrNotAvailable)
+	assert.EqualError(t, err, "governor ondemand is not available, available values: [powersave performance]")
+}
+
+func TestCpuOfPath(t *testing.T) {
+	assert.Equal(t, 12, cpuOfPath("/sys/devices/system/cpu/cpu12/cpufreq/scaling_governor"))
+	assert.Equal(t, 0, cpuOfPath("testing/cpus/cpu0/power/energy_perf_bias"))
+	assert.Equal(t, -1, cpuOfPath("/sys/devices/system/cpu/cpuidle/current_governor"))
+	assert.Equal(t, -1, cpuOfPath("/sys/devices/system/cpu/intel_uncore_frequency/package_00_die_00/max_freq_khz"))
+}
+
+func TestSysfsWriteError(t *testing.T) {
+	sysfsWrites.reset(false)
+	defer sysfsWrites.reset(false)
+
+	file := filepath.Join(t.TempDir(), "cpu4", "missing", "file")
+	err := writeSysfsValue(file, "1")
+	assert.ErrorIs(t, err, ErrSysfsWrite)
+	var writeErr *SysfsWriteError
+	assert.ErrorAs(t, err, &writeErr)
+	assert.Equal(t, file, writeErr.Path)
+	assert.Equal(t, 4, writeErr.Cpu)
+	assert.Equal(t, "1", writeErr.Value)
+	assert.True(t, errors.Is(err, writeErr.Err))
+	assert.ErrorContains(t, err, "failed to write \"1\" to "+file)
+}
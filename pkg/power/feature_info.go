@@ -0,0 +1,115 @@
+package power
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+func (id FeatureID) String() string {
+	for _, definition := range featureDefinitions {
+		if definition.id == id {
+			return definition.name
+		}
+	}
+	return fmt.Sprintf("feature-%d", uint(id))
+}
+
+func (id FeatureID) MarshalText() ([]byte, error) {
+	return []byte(id.String()), nil
+}
+
+func (id *FeatureID) UnmarshalText(text []byte) error {
+	parsed, err := ParseFeatureID(string(text))
+	if err != nil {
+		return err
+	}
+	*id = parsed
+	return nil
+}
+
+// ParseFeatureID returns the feature with the given stable name
+func ParseFeatureID(name string) (FeatureID, error) {
+	for _, definition := range featureDefinitions {
+		if definition.name == name {
+			return definition.id, nil
+		}
+	}
+	return 0, &FeatureError{Name: name, Err: fmt.Errorf("unknown feature %s: %w", name, undefinederr)}
+}
+
+// FeatureIDs returns all features defined by the library, ordered by id
+func FeatureIDs() []FeatureID {
+	ids := make([]FeatureID, 0, len(featureDefinitions))
+	for _, definition := range featureDefinitions {
+		ids = append(ids, definition.id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// FeatureInfo describes state of a feature on the current system, for diagnostics
+type FeatureInfo struct {
+	ID     FeatureID `json:"id"`
+	Name   string    `json:"name"`
+	Driver string    `json:"driver,omitempty"`
+	// feature can be used, otherwise Error describes the reason
+	Supported bool   `json:"supported"`
+	Error     string `json:"error,omitempty"`
+	// sysfs files and directories read or written by the feature, cpu* stands for every cpu
+	Paths []string `json:"paths,omitempty"`
+	// feature specific details, eg. available governors, c-states or uncore frequency ranges
+	Capabilities map[string]any `json:"capabilities,omitempty"`
+}
+
+// sysfs paths and capabilities of an initialised feature
+type featureDetails struct {
+	paths        []string
+	capabilities map[string]any
+}
+
+// Info returns info of the feature, false if the feature is not defined
+func (set *FeatureSet) Info(id FeatureID) (FeatureInfo, bool) {
+	feature, exists := (*set)[id]
+	if !exists {
+		return FeatureInfo{}, false
+	}
+	info := FeatureInfo{
+		ID:        id,
+		Name:      feature.name,
+		Driver:    feature.driver,
+		Supported: feature.isSupported(),
+	}
+	if feature.err != nil {
+		info.Error = feature.err.Error()
+	}
+	// details depend on state populated during initialisation
+	if feature.details != nil && feature.err != uninitialisedErr {
+		details := feature.details()
+		info.Paths = details.paths
+		info.Capabilities = details.capabilities
+	}
+	return info, true
+}
+
+// Infos returns info of all features in the set, ordered by id
+func (set *FeatureSet) Infos() []FeatureInfo {
+	infos := make([]FeatureInfo, 0, len(*set))
+	for id := range *set {
+		info, _ := set.Info(id)
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].ID < infos[j].ID })
+	return infos
+}
+
+// MarshalJSON serializes the set as a list of FeatureInfo
+func (set FeatureSet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(set.Infos())
+}
+
+// path of a file in directories of all cpus
+func allCpusPath(file string) string {
+	return filepath.Join(basePath, "cpu*", file)
+}
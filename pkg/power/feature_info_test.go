@@ -0,0 +1,111 @@
+package power
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeatureID_String(t *testing.T) {
+	assert.Equal(t, "frequency-scaling", FrequencyScalingFeature.String())
+	assert.Equal(t, "idle-governor", IdleGovernorFeature.String())
+	assert.Equal(t, "feature-100", FeatureID(100).String())
+
+	for _, id := range FeatureIDs() {
+		parsed, err := ParseFeatureID(id.String())
+		assert.NoError(t, err)
+		assert.Equal(t, id, parsed)
+	}
+	assert.Len(t, FeatureIDs(), len(featureList))
+
+	_, err := ParseFeatureID("turbo")
+	assert.ErrorIs(t, err, ErrFeatureUndefined)
+
+	var ids []FeatureID
+	assert.NoError(t, json.Unmarshal([]byte(`["epp","c-states"]`), &ids))
+	assert.Equal(t, []FeatureID{EPPFeature, CStatesFeature}, ids)
+	assert.Error(t, json.Unmarshal([]byte(`["turbo"]`), &ids))
+}
+
+func TestFeatureID_allNamed(t *testing.T) {
+	names := map[string]FeatureID{}
+	for id := FrequencyScalingFeature; id <= EPBFeature; id++ {
+		name := id.String()
+		assert.NotEqual(t, fmt.Sprintf("feature-%d", uint(id)), name, "feature %d has no name", uint(id))
+		assert.NotContains(t, names, name, "name %s is not unique", name)
+		names[name] = id
+	}
+	assert.Len(t, featureDefinitions, len(names))
+
+	set := newFeatureSet()
+	assert.Len(t, set, len(featureDefinitions))
+	for _, id := range FeatureIDs() {
+		assert.Contains(t, set, id)
+	}
+}
+
+func TestFeatureSet_Info(t *testing.T) {
+	set := FeatureSet{
+		EPBFeature: &featureStatus{name: "EPB", err: fmt.Errorf("EPB file missing"), details: epbDetails},
+		PMQoSFeature: &featureStatus{name: "PM QoS", err: uninitialisedErr, details: func() featureDetails {
+			panic("details of uninitialised feature")
+		}},
+		EPPFeature: &featureStatus{name: "EPP", driver: "intel_pstate", details: func() featureDetails {
+			return featureDetails{paths: []string{"epp"}, capabilities: map[string]any{"preferences": []string{"performance"}}}
+		}},
+	}
+
+	_, exists := set.Info(UncoreFeature)
+	assert.False(t, exists)
+
+	info, exists := set.Info(EPPFeature)
+	assert.True(t, exists)
+	assert.Equal(t, FeatureInfo{
+		ID:           EPPFeature,
+		Name:         "EPP",
+		Driver:       "intel_pstate",
+		Supported:    true,
+		Paths:        []string{"epp"},
+		Capabilities: map[string]any{"preferences": []string{"performance"}},
+	}, info)
+
+	info, _ = set.Info(PMQoSFeature)
+	assert.False(t, info.Supported)
+	assert.Equal(t, uninitialisedErr.Error(), info.Error)
+	assert.Nil(t, info.Paths)
+
+	infos := set.Infos()
+	assert.Len(t, infos, 3)
+	assert.Equal(t, EPPFeature, infos[0].ID)
+	assert.Equal(t, PMQoSFeature, infos[1].ID)
+	assert.Equal(t, EPBFeature, infos[2].ID)
+	assert.Equal(t, []string{"performance", "balance-performance", "normal", "balance-power", "power"}, infos[2].Capabilities["presets"])
+
+	serialized, err := json.Marshal(set)
+	assert.NoError(t, err)
+	var decoded []map[string]any
+	assert.NoError(t, json.Unmarshal(serialized, &decoded))
+	assert.Len(t, decoded, 3)
+	assert.Equal(t, "epp", decoded[0]["id"])
+	assert.Equal(t, true, decoded[0]["supported"])
+	assert.NotContains(t, decoded[0], "error")
+	assert.Equal(t, "pm-qos", decoded[1]["id"])
+	assert.Equal(t, uninitialisedErr.Error(), decoded[1]["error"])
+	assert.NotContains(t, decoded[1], "capabilities")
+}
+
+func TestFeatureInfo_idleGovernor(t *testing.T) {
+	defer setupIdleGovernorTests("menu teo", "menu")()
+	feature := initIdleGovernor()
+	featureList[IdleGovernorFeature] = &feature
+
+	info, exists := featureList.Info(IdleGovernorFeature)
+	assert.True(t, exists)
+	assert.True(t, info.Supported)
+	assert.Equal(t, "menu", info.Driver)
+	assert.Equal(t, []string{filepath.Join(basePath, idleGovernorFile), filepath.Join(basePath, availIdleGovernorsFile)}, info.Paths)
+	assert.Equal(t, []string{"menu", "teo"}, info.Capabilities["governors"])
+}
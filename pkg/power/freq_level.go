@@ -0,0 +1,180 @@
+package power
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const baseFreqFile = "cpufreq/base_frequency"
+
+// FreqLevel is a frequency expressed relative to properties of each cpu instead of an absolute value,
+// resolved when the profile is applied to a cpu. besides the named levels a percentage of the cpuinfo
+// frequency range of the cpu core type can be used, eg. "60%"
+type FreqLevel string
+
+const (
+	// guaranteed base frequency of the cpu, differs between cpus on SST-BF SKUs
+	FreqLevelBase FreqLevel = "base"
+	// cpuinfo min frequency of the core type
+	FreqLevelMin FreqLevel = "min"
+	// cpuinfo max frequency of the core type, including turbo on intel_pstate
+	FreqLevelMaxTurbo FreqLevel = "max-turbo"
+)
+
+func validateFreqLevel(level FreqLevel) error {
+	switch level {
+	case FreqLevelBase, FreqLevelMin, FreqLevelMaxTurbo:
+		return nil
+	}
+	_, err := parsePercentLevel(level)
+	return err
+}
+
+// returns position of a level within the cpuinfo range in percent, false if it depends on the cpu like base
+func freqLevelPercent(level FreqLevel) (uint, bool) {
+	switch level {
+	case FreqLevelMin:
+		return 0, true
+	case FreqLevelMaxTurbo:
+		return 100, true
+	case FreqLevelBase, "":
+		return 0, false
+	}
+	percent, err := parsePercentLevel(level)
+	return percent, err == nil
+}
+
+// rejects min and max levels in the wrong order where the order does not depend on the cpu
+func validateFreqLevelOrder(minLevel FreqLevel, maxLevel FreqLevel) error {
+	minPercent, minKnown := freqLevelPercent(minLevel)
+	maxPercent, maxKnown := freqLevelPercent(maxLevel)
+	if minKnown && maxKnown && minPercent > maxPercent {
+		return fmt.Errorf("min frequency level %s is higher than max frequency level %s", minLevel, maxLevel)
+	}
+	return nil
+}
+
+func parsePercentLevel(level FreqLevel) (uint, error) {
+	value, isPercent := strings.CutSuffix(string(level), "%")
+	if !isPercent {
+		return 0, fmt.Errorf("unknown frequency level %s", level)
+	}
+	percent, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("frequency level %s has to be a percentage within 0-100", level)
+	}
+	if percent > 100 {
+		return 0, &OutOfRangeError{Setting: "frequency level percentage", Value: uint(percent), Min: 0, Max: 100}
+	}
+	return uint(percent), nil
+}
+
+// NewRelativePowerProfile creates a power profile with min and max frequencies expressed as levels, resolved per
+// core type and cpu when applied, so the same profile definition can be used on different SKUs.
+// MinFreq and MaxFreq of such a profile return 0
+func NewRelativePowerProfile(name string, minLevel FreqLevel, maxLevel FreqLevel, governor string, epp string, options ...ProfileOption) (Profile, error) {
+	if !featureList.isFeatureIdSupported(FrequencyScalingFeature) {
+		return nil, featureList.getFeatureIdError(FrequencyScalingFeature)
+	}
+	if governor == "" {
+		governor = defaultGovernor
+	}
+	if !checkGov(governor) {
+		return nil, &NotAvailableError{Setting: "governor", Value: governor, Available: availableGovs}
+	}
+	if err := validateEpp(epp, governor); err != nil {
+		return nil, err
+	}
+	log.Info("creating relative powerProfile object", "name", name, "min", minLevel, "max", maxLevel)
+	profile := &profileImpl{
+		name:     name,
+		epp:      epp,
+		governor: governor,
+	}
+	options = append([]ProfileOption{WithMinFreqLevel(minLevel), WithMaxFreqLevel(maxLevel)}, options...)
+	if err := profile.applyOptions(options); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// WithMinFreqLevel sets min frequency of the profile to a level resolved for each cpu, eg. its base frequency,
+// replacing min frequencies passed to the constructor
+func WithMinFreqLevel(level FreqLevel) ProfileOption {
+	return func(profile *profileImpl) error {
+		if err := validateFreqLevel(level); err != nil {
+			return err
+		}
+		profile.minLevel = level
+		return nil
+	}
+}
+
+// WithMaxFreqLevel sets max frequency of the profile to a level resolved for each cpu, replacing max frequencies
+// passed to the constructor
+func WithMaxFreqLevel(level FreqLevel) ProfileOption {
+	return func(profile *profileImpl) error {
+		if err := validateFreqLevel(level); err != nil {
+			return err
+		}
+		profile.maxLevel = level
+		return nil
+	}
+}
+
+// resolves a frequency level for a cpu in kHz
+func (cpu *cpuImpl) resolveFreqLevel(level FreqLevel) (uint, error) {
+	if level == FreqLevelBase {
+		if cpu.baseFreq == 0 {
+			return 0, fmt.Errorf("base frequency of cpu %d is unknown", cpu.id)
+		}
+		return cpu.baseFreq, nil
+	}
+	typeNum := cpu.GetCore().GetType()
+	if typeNum >= uint(len(coreTypes)) {
+		return 0, fmt.Errorf("frequency range of cpu %d is unknown", cpu.id)
+	}
+	absMin, absMax := coreTypes[typeNum].GetMin(), coreTypes[typeNum].GetMax()
+	switch level {
+	case FreqLevelMin:
+		return absMin, nil
+	case FreqLevelMaxTurbo:
+		return absMax, nil
+	}
+	percent, err := parsePercentLevel(level)
+	if err != nil {
+		return 0, err
+	}
+	available, err := readAvailableFrequencies(cpu.id)
+	if err != nil {
+		return 0, err
+	}
+	freq := normalizeCpuFreq(absMin+(absMax-absMin)*percent/100, available)
+	// normalization must not leave the cpuinfo range
+	return min(max(freq, absMin), absMax), nil
+}
+
+func (cpu *cpuImpl) GetBaseFrequency() uint {
+	return cpu.baseFreq
+}
+
+// HighPriorityCpus returns cpus with base frequency higher than the lowest base frequency of their core type,
+// on SST-BF enabled SKUs these are the high priority cores. empty if all cpus of a type share the same base frequency
+func (host *hostImpl) HighPriorityCpus() CpuList {
+	cpus := make(CpuList, 0)
+	// base frequencies of P-cores and E-cores differ without SST-BF, so only cpus of the same type are compared
+	lowest := map[uint]uint{}
+	for _, cpu := range *host.GetAllCpus() {
+		coreType := cpu.GetCore().GetType()
+		if base := cpu.GetBaseFrequency(); base != 0 && (lowest[coreType] == 0 || base < lowest[coreType]) {
+			lowest[coreType] = base
+		}
+	}
+	for _, cpu := range *host.GetAllCpus() {
+		if cpu.GetBaseFrequency() > lowest[cpu.GetCore().GetType()] {
+			cpus.add(cpu)
+		}
+	}
+	return cpus
+}
@@ -0,0 +1,197 @@
+package power
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCpuBaseFrequency(t *testing.T) {
+	defer setupCpuScalingTests(map[string]map[string]string{
+		"cpu0": {"max": "3500000", "min": "800000"},
+		"cpu1": {"max": "3500000", "min": "800000"},
+	})()
+	assert.NoError(t, os.WriteFile(filepath.Join(basePath, "cpu0", baseFreqFile), []byte("2700000\n"), 0644))
+
+	cpu, err := newCpu(0, &cpuCore{})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2_700_000), cpu.GetBaseFrequency())
+
+	// not reported
+	cpu, err = newCpu(1, &cpuCore{})
+	assert.NoError(t, err)
+	assert.Equal(t, uint(0), cpu.GetBaseFrequency())
+}
+
+func TestHostImpl_HighPriorityCpus(t *testing.T) {
+	core := &cpuCore{}
+	cpus := CpuList{
+		&cpuImpl{id: 0, baseFreq: 2_100_000, core: core},
+		&cpuImpl{id: 1, baseFreq: 2_700_000, core: core},
+		&cpuImpl{id: 2, baseFreq: 2_100_000, core: core},
+		&cpuImpl{id: 3, baseFreq: 2_700_000, core: core},
+	}
+	topology := new(mockCpuTopology)
+	topology.On("CPUs").Return(&cpus)
+	host := &hostImpl{topology: topology}
+	highPriority := host.HighPriorityCpus()
+	assert.Equal(t, []uint{1, 3}, highPriority.IDs())
+
+	// same base frequency everywhere
+	cpus[0].(*cpuImpl).baseFreq = 2_700_000
+	cpus[2].(*cpuImpl).baseFreq = 2_700_000
+	assert.Empty(t, host.HighPriorityCpus())
+
+	// unknown base frequency
+	cpus = CpuList{&cpuImpl{id: 0, core: core}, &cpuImpl{id: 1, core: core}}
+	assert.Empty(t, host.HighPriorityCpus())
+}
+
+func TestHostImpl_HighPriorityCpusHybrid(t *testing.T) {
+	pcore := &cpuCore{coreType: 0}
+	ecore := &cpuCore{coreType: 1}
+	cpus := CpuList{
+		&cpuImpl{id: 0, baseFreq: 2_500_000, core: pcore},
+		&cpuImpl{id: 1, baseFreq: 2_500_000, core: pcore},
+		&cpuImpl{id: 2, baseFreq: 1_800_000, core: ecore},
+		&cpuImpl{id: 3, baseFreq: 1_800_000, core: ecore},
+	}
+	topology := new(mockCpuTopology)
+	topology.On("CPUs").Return(&cpus)
+	host := &hostImpl{topology: topology}
+	// p-cores are faster than e-cores, but none has a higher base frequency than other cores of its type
+	assert.Empty(t, host.HighPriorityCpus())
+
+	cpus[1].(*cpuImpl).baseFreq = 2_900_000
+	cpus[3].(*cpuImpl).baseFreq = 2_000_000
+	highPriority := host.HighPriorityCpus()
+	assert.Equal(t, []uint{1, 3}, highPriority.IDs())
+}
+
+func TestWithFreqLevels(t *testing.T) {
+	oldGovs := availableGovs
+	availableGovs = []string{cpuPolicyPowersave}
+	defer func() { availableGovs = oldGovs }()
+	defer setupCpuScalingTests(map[string]map[string]string{"cpu0": {}})()
+
+	profile, err := NewPowerProfile("sst-bf", 800, 3500, cpuPolicyPowersave, "", WithMinFreqLevel(FreqLevelBase))
+	assert.NoError(t, err)
+	minLevel, maxLevel := profile.FreqLevels()
+	assert.Equal(t, FreqLevelBase, minLevel)
+	assert.Equal(t, FreqLevel(""), maxLevel)
+
+	_, err = NewPowerProfile("sst-bf", 800, 3500, cpuPolicyPowersave, "", WithMaxFreqLevel("turbo"))
+	assert.ErrorContains(t, err, "unknown frequency level turbo")
+}
+
+func TestCpuImpl_getFreqsToScaleLevels(t *testing.T) {
+	typeCopy := coreTypes
+	defer func() { coreTypes = typeCopy }()
+	coreTypes = CoreTypeList{&CpuFrequencySet{min: 800_000, max: 3_500_000}}
+	profile := &profileImpl{min: 800_000, max: 3_500_000, minLevel: FreqLevelBase}
+
+	cpu := &cpuImpl{id: 0, core: &cpuCore{}, baseFreq: 2_700_000}
+	minFreq, maxFreq, err := cpu.getFreqsToScale(profile)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2_700_000), minFreq)
+	assert.Equal(t, uint(3_500_000), maxFreq)
+
+	profile.maxLevel = FreqLevelBase
+	minFreq, maxFreq, err = cpu.getFreqsToScale(profile)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2_700_000), minFreq)
+	assert.Equal(t, uint(2_700_000), maxFreq)
+
+	cpu.baseFreq = 0
+	_, _, err = cpu.getFreqsToScale(profile)
+	assert.ErrorContains(t, err, "base frequency of cpu 0 is unknown")
+}
+
+func TestValidateFreqLevel(t *testing.T) {
+	for _, level := range []FreqLevel{FreqLevelBase, FreqLevelMin, FreqLevelMaxTurbo, "0%", "60%", "100%"} {
+		assert.NoError(t, validateFreqLevel(level), level)
+	}
+	assert.ErrorContains(t, validateFreqLevel("turbo"), "unknown frequency level turbo")
+	assert.ErrorIs(t, validateFreqLevel("101%"), ErrOutOfRange)
+	assert.EqualError(t, validateFreqLevel("101%"), "frequency level percentage 101 is out of range 0-100")
+	assert.ErrorContains(t, validateFreqLevel("-5%"), "has to be a percentage within 0-100")
+	assert.ErrorContains(t, validateFreqLevel("%"), "has to be a percentage within 0-100")
+}
+
+func TestNewRelativePowerProfile(t *testing.T) {
+	oldGovs := availableGovs
+	availableGovs = []string{cpuPolicyPowersave, cpuPolicyPerformance}
+	defer func() { availableGovs = oldGovs }()
+	defer setupCpuScalingTests(map[string]map[string]string{"cpu0": {}})()
+
+	profile, err := NewRelativePowerProfile("balance-performance", "50%", FreqLevelMaxTurbo, cpuPolicyPowersave, "")
+	assert.NoError(t, err)
+	minLevel, maxLevel := profile.FreqLevels()
+	assert.Equal(t, FreqLevel("50%"), minLevel)
+	assert.Equal(t, FreqLevelMaxTurbo, maxLevel)
+	assert.Equal(t, uint(0), profile.MinFreq())
+	assert.Equal(t, uint(0), profile.MaxFreq())
+
+	_, err = NewRelativePowerProfile("bad", "150%", FreqLevelMaxTurbo, cpuPolicyPowersave, "")
+	assert.ErrorIs(t, err, ErrOutOfRange)
+
+	_, err = NewRelativePowerProfile("bad", FreqLevelMin, FreqLevelMaxTurbo, "ondemand", "")
+	assert.ErrorIs(t, err, ErrNotAvailable)
+
+	// levels in the wrong order
+	_, err = NewRelativePowerProfile("bad", FreqLevelMaxTurbo, FreqLevelMin, cpuPolicyPowersave, "")
+	assert.ErrorContains(t, err, "min frequency level max-turbo is higher than max frequency level min")
+	_, err = NewRelativePowerProfile("bad", "80%", "50%", cpuPolicyPowersave, "")
+	assert.ErrorContains(t, err, "min frequency level 80% is higher than max frequency level 50%")
+	_, err = NewPowerProfile("bad", 800, 3500, cpuPolicyPowersave, "", WithMinFreqLevel("60%"), WithMaxFreqLevel(FreqLevelMin))
+	assert.ErrorContains(t, err, "is higher than max frequency level")
+	// base depends on the cpu, checked when applied
+	_, err = NewRelativePowerProfile("sst-bf", FreqLevelBase, "50%", cpuPolicyPowersave, "")
+	assert.NoError(t, err)
+
+	featureList[FrequencyScalingFeature].err = fmt.Errorf("no scaling")
+	defer func() { featureList[FrequencyScalingFeature].err = nil }()
+	_, err = NewRelativePowerProfile("bad", FreqLevelMin, FreqLevelMaxTurbo, cpuPolicyPowersave, "")
+	assert.ErrorContains(t, err, "no scaling")
+}
+
+func TestCpuImpl_resolveFreqLevelPerCoreType(t *testing.T) {
+	typeCopy := coreTypes
+	defer func() { coreTypes = typeCopy }()
+	defer setupCpuScalingTests(map[string]map[string]string{"cpu0": {}, "cpu1": {}})()
+	coreTypes = CoreTypeList{
+		&CpuFrequencySet{min: 800_000, max: 5_000_000},
+		&CpuFrequencySet{min: 800_000, max: 3_800_000},
+	}
+	profile := &profileImpl{minLevel: "50%", maxLevel: FreqLevelMaxTurbo}
+
+	pCore := &cpuImpl{id: 0, core: &cpuCore{coreType: 0}}
+	minFreq, maxFreq, err := pCore.getFreqsToScale(profile)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2_900_000), minFreq)
+	assert.Equal(t, uint(5_000_000), maxFreq)
+
+	eCore := &cpuImpl{id: 1, core: &cpuCore{coreType: 1}}
+	minFreq, maxFreq, err = eCore.getFreqsToScale(profile)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2_300_000), minFreq)
+	assert.Equal(t, uint(3_800_000), maxFreq)
+
+	profile.minLevel = FreqLevelMin
+	minFreq, _, err = eCore.getFreqsToScale(profile)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(800_000), minFreq)
+
+	// percentages snap to discrete frequencies when the driver reports them
+	assert.NoError(t, os.WriteFile(filepath.Join(basePath, "cpu1", availFreqsFile), []byte("3800000 3000000 2200000 800000\n"), 0644))
+	freq, err := eCore.resolveFreqLevel("50%")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2_200_000), freq)
+
+	unknownType := &cpuImpl{id: 0, core: &cpuCore{coreType: 5}}
+	_, err = unknownType.resolveFreqLevel(FreqLevelMin)
+	assert.ErrorContains(t, err, "frequency range of cpu 0 is unknown")
+}
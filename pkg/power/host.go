@@ -9,10 +9,12 @@ import (
 type hostImpl struct {
 	name           string
 	exclusivePools PoolList
-	reservedPool   Pool
-	sharedPool     Pool
-	topology       Topology
-	featureStates  *FeatureSet
+	// guards exclusivePools, never held while acquiring another lock
+	poolsMutex    sync.Mutex
+	reservedPool  Pool
+	sharedPool    Pool
+	topology      Topology
+	featureStates *FeatureSet
 }
 
 // Host represents the actual machine to be managed
@@ -28,13 +30,27 @@ type Host interface {
 	GetExclusivePool(poolName string) Pool
 	GetAllExclusivePools() *PoolList
 
+	AllocateCpus(poolName string, request CpuAllocation) (CpuList, error)
+	ReleaseCpus(poolName string, cpus CpuList) error
+	// cpus with higher base frequency on SST-BF enabled systems
+	HighPriorityCpus() CpuList
+
 	GetAllCpus() *CpuList
 	GetFreqRanges() CoreTypeList
 	Topology() Topology
 	// returns number of distinct core types
 	NumCoreTypes() uint
-	AvailableCStates() []string
+	AvailableCStates() []CStateInfo
 	ValidateCStates(states CStates) error
+	// cpuidle governor, system-wide
+	AvailableIdleGovernors() []string
+	GetIdleGovernor() (string, error)
+	SetIdleGovernor(governor string) error
+	ResetIdleGovernor() error
+	// standard profiles computed from discovered frequencies, governors and epp
+	PresetProfile(preset ProfilePreset) (Profile, error)
+
+	removeExclusivePool(pool Pool) error
 }
 
 // create a pre-populated Host object
@@ -121,8 +137,19 @@ func (host *hostImpl) GetFreqRanges() CoreTypeList {
 
 // AddExclusivePool creates new empty pool
 func (host *hostImpl) AddExclusivePool(poolName string) (Pool, error) {
+	pool, created := host.getOrAddExclusivePool(poolName)
+	if !created {
+		return pool, fmt.Errorf("pool with name %s already exists", poolName)
+	}
+	return pool, nil
+}
+
+// getOrAddExclusivePool returns the pool with the name, creating it if it does not exist yet, true if it was created
+func (host *hostImpl) getOrAddExclusivePool(poolName string) (Pool, bool) {
+	host.poolsMutex.Lock()
+	defer host.poolsMutex.Unlock()
 	if i := host.exclusivePools.IndexOfName(poolName); i >= 0 {
-		return host.exclusivePools[i], fmt.Errorf("pool with name %s already exists", poolName)
+		return host.exclusivePools[i], false
 	}
 	var pool Pool = &exclusivePoolType{poolImpl{
 		name:  poolName,
@@ -132,15 +159,23 @@ func (host *hostImpl) AddExclusivePool(poolName string) (Pool, error) {
 	}}
 
 	host.exclusivePools.add(pool)
-	return pool, nil
+	return pool, true
 }
 
 // GetExclusivePool Returns a Pool object of the exclusive pool with matching name supplied
 // returns nil if not found
 func (host *hostImpl) GetExclusivePool(name string) Pool {
+	host.poolsMutex.Lock()
+	defer host.poolsMutex.Unlock()
 	return host.exclusivePools.ByName(name)
 }
 
+func (host *hostImpl) removeExclusivePool(pool Pool) error {
+	host.poolsMutex.Lock()
+	defer host.poolsMutex.Unlock()
+	return host.exclusivePools.remove(pool)
+}
+
 // GetSharedPool returns shared pool
 func (host *hostImpl) GetSharedPool() Pool {
 	return host.sharedPool
@@ -22,14 +22,54 @@ func (m *hostMock) ValidateCStates(states CStates) error {
 	return m.Called(states).Error(0)
 }
 
-func (m *hostMock) AvailableCStates() []string {
-	return m.Called().Get(0).([]string)
+func (m *hostMock) AvailableCStates() []CStateInfo {
+	return m.Called().Get(0).([]CStateInfo)
 }
 
 func (m *hostMock) GetAllExclusivePools() *PoolList {
 	return m.Called().Get(0).(*PoolList)
 }
 
+func (m *hostMock) AllocateCpus(poolName string, request CpuAllocation) (CpuList, error) {
+	args := m.Called(poolName, request)
+	cpus := args.Get(0)
+	if cpus == nil {
+		return nil, args.Error(1)
+	}
+	return cpus.(CpuList), args.Error(1)
+}
+
+func (m *hostMock) HighPriorityCpus() CpuList {
+	return m.Called().Get(0).(CpuList)
+}
+
+func (m *hostMock) PresetProfile(preset ProfilePreset) (Profile, error) {
+	args := m.Called(preset)
+	retProfile, _ := args.Get(0).(Profile)
+	return retProfile, args.Error(1)
+}
+
+func (m *hostMock) ReleaseCpus(poolName string, cpus CpuList) error {
+	return m.Called(poolName, cpus).Error(0)
+}
+
+func (m *hostMock) AvailableIdleGovernors() []string {
+	return m.Called().Get(0).([]string)
+}
+
+func (m *hostMock) GetIdleGovernor() (string, error) {
+	args := m.Called()
+	return args.String(0), args.Error(1)
+}
+
+func (m *hostMock) SetIdleGovernor(governor string) error {
+	return m.Called(governor).Error(0)
+}
+
+func (m *hostMock) ResetIdleGovernor() error {
+	return m.Called().Error(0)
+}
+
 func (m *hostMock) SetName(name string) {
 	m.Called(name)
 }
@@ -79,6 +119,10 @@ func (m *hostMock) AddExclusivePool(poolName string) (Pool, error) {
 	}
 }
 
+func (m *hostMock) removeExclusivePool(pool Pool) error {
+	return m.Called(pool).Error(0)
+}
+
 func (m *hostMock) GetExclusivePool(poolName string) Pool {
 	ret := m.Called(poolName).Get(0)
 	if ret == nil {
@@ -174,7 +218,7 @@ func (s *hostTestsSuite) TestRemoveExclusivePool() {
 	host := &hostImpl{
 		exclusivePools: []Pool{p1, p2},
 	}
-	s.NoError(host.GetAllExclusivePools().remove(p1))
+	s.NoError(host.removeExclusivePool(p1))
 	s.Assert().NotContains(host.exclusivePools, p1)
 	s.Assert().Contains(host.exclusivePools, p2)
 
@@ -182,7 +226,7 @@ func (s *hostTestsSuite) TestRemoveExclusivePool() {
 	p3 := new(poolMock)
 	p3.On("Name").Return("pool3")
 	p3.On("Remove").Return(nil)
-	s.Error(new(hostImpl).GetAllExclusivePools().remove(p3))
+	s.Error(new(hostImpl).removeExclusivePool(p3))
 }
 
 func (s *hostTestsSuite) TestHostImpl_SetReservedPoolCores() {
@@ -329,7 +373,7 @@ func (s *hostTestsSuite) TestUpdateProfile() {
 		featureStates: &FeatureSet{FrequencyScalingFeature: &featureStatus{err: nil}},
 	}
 	origFeatureList := featureList
-	featureList = map[featureID]*featureStatus{
+	featureList = map[FeatureID]*featureStatus{
 		FrequencyScalingFeature: {
 			err:      nil,
 			initFunc: initScalingDriver,
@@ -0,0 +1,124 @@
+package power
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	idleGovernorFile       = cStatesDir + "/current_governor"
+	availIdleGovernorsFile = cStatesDir + "/available_governors"
+)
+
+var (
+	// populated during library initialisation
+	availableIdleGovs []string
+	// governor in use when the library was initialised, restored by ResetIdleGovernor
+	defaultIdleGovernor string
+	idleGovernorMutex   sync.Mutex
+)
+
+func initIdleGovernor() featureStatus {
+	feature := featureStatus{
+		name:     "Idle-Governor",
+		initFunc: initIdleGovernor,
+		details:  idleGovernorDetails,
+	}
+	govs, err := readStringFromFile(filepath.Join(basePath, availIdleGovernorsFile))
+	if err != nil {
+		feature.err = fmt.Errorf("failed to read available idle governors: %w", err)
+		return feature
+	}
+	availableIdleGovs = strings.Fields(govs)
+	current, err := readStringFromFile(filepath.Join(basePath, idleGovernorFile))
+	if err != nil {
+		feature.err = fmt.Errorf("failed to read current idle governor: %w", err)
+		return feature
+	}
+	defaultIdleGovernor = strings.TrimSpace(current)
+	// the governor in use at initialisation is reported as the driver of the feature, the current one is read
+	// from sysfs as feature entries are shared and not updated after initialisation
+	feature.driver = defaultIdleGovernor
+	return feature
+}
+
+func idleGovernorDetails() featureDetails {
+	return featureDetails{
+		paths: []string{
+			filepath.Join(basePath, idleGovernorFile),
+			filepath.Join(basePath, availIdleGovernorsFile),
+		},
+		capabilities: map[string]any{"governors": availableIdleGovs, "current": currentIdleGovernor()},
+	}
+}
+
+// returns the governor in use, empty if it cannot be read
+func currentIdleGovernor() string {
+	governor, err := readStringFromFile(filepath.Join(basePath, idleGovernorFile))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(governor)
+}
+
+// AvailableIdleGovernors returns cpuidle governors available on the system, eg. menu, teo, ladder, haltpoll
+func (host *hostImpl) AvailableIdleGovernors() []string {
+	if !IsFeatureSupported(IdleGovernorFeature) {
+		return []string{}
+	}
+	return availableIdleGovs
+}
+
+// GetIdleGovernor returns the cpuidle governor currently in use
+func (host *hostImpl) GetIdleGovernor() (string, error) {
+	if !IsFeatureSupported(IdleGovernorFeature) {
+		return "", featureList.getFeatureIdError(IdleGovernorFeature)
+	}
+	governor, err := readStringFromFile(filepath.Join(basePath, idleGovernorFile))
+	if err != nil {
+		return "", fmt.Errorf("failed to read current idle governor: %w", err)
+	}
+	return strings.TrimSpace(governor), nil
+}
+
+// SetIdleGovernor selects the system-wide cpuidle governor
+func (host *hostImpl) SetIdleGovernor(governor string) error {
+	if !IsFeatureSupported(IdleGovernorFeature) {
+		return featureList.getFeatureIdError(IdleGovernorFeature)
+	}
+	if !isIdleGovernorAvailable(governor) {
+		return &NotAvailableError{Setting: "idle governor", Value: governor, Available: availableIdleGovs}
+	}
+	return writeIdleGovernor(governor)
+}
+
+// ResetIdleGovernor restores the cpuidle governor that was in use when the library was initialised
+func (host *hostImpl) ResetIdleGovernor() error {
+	if !IsFeatureSupported(IdleGovernorFeature) {
+		return featureList.getFeatureIdError(IdleGovernorFeature)
+	}
+	return writeIdleGovernor(defaultIdleGovernor)
+}
+
+func isIdleGovernorAvailable(governor string) bool {
+	for _, available := range availableIdleGovs {
+		if available == governor {
+			return true
+		}
+	}
+	return false
+}
+
+func writeIdleGovernor(governor string) error {
+	idleGovernorMutex.Lock()
+	defer idleGovernorMutex.Unlock()
+	governorPath := filepath.Join(basePath, idleGovernorFile)
+	if err := os.WriteFile(governorPath, []byte(governor), 0644); err != nil {
+		return fmt.Errorf("failed to set idle governor %s: %w", governor, newSysfsWriteError(governorPath, governor, err))
+	}
+	log.Info("idle governor set", "governor", governor)
+	return nil
+}
@@ -0,0 +1,110 @@
+package power
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupIdleGovernorTests(available string, current string) func() {
+	origBasePath := basePath
+	basePath = "testing/cpus"
+
+	if err := os.MkdirAll(filepath.Join(basePath, cStatesDir), os.ModePerm); err != nil {
+		panic(err)
+	}
+	if available != "" {
+		if err := os.WriteFile(filepath.Join(basePath, availIdleGovernorsFile), []byte(available), 0644); err != nil {
+			panic(err)
+		}
+	}
+	if current != "" {
+		if err := os.WriteFile(filepath.Join(basePath, idleGovernorFile), []byte(current), 0644); err != nil {
+			panic(err)
+		}
+	}
+	return func() {
+		if err := os.RemoveAll(strings.Split(basePath, "/")[0]); err != nil {
+			panic(err)
+		}
+		basePath = origBasePath
+		availableIdleGovs = nil
+		defaultIdleGovernor = ""
+		featureList[IdleGovernorFeature] = &featureStatus{err: uninitialisedErr, initFunc: initIdleGovernor}
+	}
+}
+
+func Test_initIdleGovernor(t *testing.T) {
+	teardown := setupIdleGovernorTests("menu teo haltpoll\n", "menu\n")
+	feature := initIdleGovernor()
+	assert.NoError(t, feature.err)
+	assert.Equal(t, "menu", feature.driver)
+	assert.Equal(t, []string{"menu", "teo", "haltpoll"}, availableIdleGovs)
+	assert.Equal(t, "menu", defaultIdleGovernor)
+	teardown()
+
+	teardown = setupIdleGovernorTests("", "menu\n")
+	assert.ErrorContains(t, initIdleGovernor().err, "failed to read available idle governors")
+	teardown()
+
+	teardown = setupIdleGovernorTests("menu teo\n", "")
+	assert.ErrorContains(t, initIdleGovernor().err, "failed to read current idle governor")
+	teardown()
+}
+
+func TestHostImpl_SetIdleGovernor(t *testing.T) {
+	host := &hostImpl{}
+	defer setupIdleGovernorTests("menu teo\n", "menu\n")()
+
+	// not supported
+	assert.Empty(t, host.AvailableIdleGovernors())
+	assert.ErrorIs(t, host.SetIdleGovernor("teo"), uninitialisedErr)
+	_, err := host.GetIdleGovernor()
+	assert.ErrorIs(t, err, uninitialisedErr)
+	assert.ErrorIs(t, host.ResetIdleGovernor(), uninitialisedErr)
+
+	feature := initIdleGovernor()
+	featureList[IdleGovernorFeature] = &feature
+	assert.Equal(t, []string{"menu", "teo"}, host.AvailableIdleGovernors())
+
+	assert.NoError(t, host.SetIdleGovernor("teo"))
+	governor, err := host.GetIdleGovernor()
+	assert.NoError(t, err)
+	assert.Equal(t, "teo", governor)
+	// driver stays the one at initialisation, info reports the current governor
+	assert.Equal(t, "menu", featureList[IdleGovernorFeature].Driver())
+	info, _ := featureList.Info(IdleGovernorFeature)
+	assert.Equal(t, "teo", info.Capabilities["current"])
+
+	assert.ErrorIs(t, host.SetIdleGovernor("ladder"), ErrNotAvailable)
+
+	assert.NoError(t, host.ResetIdleGovernor())
+	governor, _ = host.GetIdleGovernor()
+	assert.Equal(t, "menu", governor)
+
+	// governor changes race with reading feature info
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			assert.NoError(t, host.SetIdleGovernor([]string{"menu", "teo"}[i%2]))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			_, err := json.Marshal(featureList)
+			assert.NoError(t, err)
+		}
+	}()
+	wg.Wait()
+
+	assert.NoError(t, os.RemoveAll(filepath.Join(basePath, cStatesDir)))
+	assert.ErrorContains(t, host.SetIdleGovernor("teo"), "failed to set idle governor teo")
+}
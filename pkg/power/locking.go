@@ -0,0 +1,86 @@
+package power
+
+import (
+	"sort"
+)
+
+/*
+Lock hierarchy, locks are always acquired top to bottom and never while holding a lock of a lower level:
+
+ 1. pool mutexes, in the order of poolLockRank: reserved pool, shared pool, then exclusive pools by name
+ 2. cpu mutex
+ 3. die mutex of uncore and idle governor mutex
+ 4. lock of a file in the sysfs write cache, held while its value is compared, written and cached
+ 5. sysfs write cache mutex, never held while acquiring another lock
+
+The host mutex guarding the list of exclusive pools and the mutex of shared governor tunable claims are never held
+while acquiring another lock, allocation looks up or creates its pool before locking the pools.
+
+A cpu can only be moved while both its current and target pools are locked, so holding the mutex of a pool keeps
+its cpu list and the settings cpus read from it stable. Pool operations consolidating cpus hold the pool mutex and
+take the mutex of each cpu, possibly from several goroutines. Operations on a single cpu lock its pool first.
+Allocation locks the shared and target pools for both selection and moving of cpus.
+*/
+
+func poolLockRank(pool Pool) int {
+	switch pool.(type) {
+	case *reservedPoolType:
+		return 0
+	case *sharedPoolType:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// lockPools locks distinct pools in hierarchy order and returns a function unlocking them
+func lockPools(pools ...Pool) func() {
+	ordered := make([]Pool, 0, len(pools))
+	for _, pool := range pools {
+		if pool != nil && !containsPool(ordered, pool) {
+			ordered = append(ordered, pool)
+		}
+	}
+	sort.SliceStable(ordered, func(i, j int) bool {
+		rankI, rankJ := poolLockRank(ordered[i]), poolLockRank(ordered[j])
+		if rankI != rankJ {
+			return rankI < rankJ
+		}
+		return ordered[i].Name() < ordered[j].Name()
+	})
+	for _, pool := range ordered {
+		pool.poolMutex().Lock()
+	}
+	return func() {
+		for i := len(ordered) - 1; i >= 0; i-- {
+			ordered[i].poolMutex().Unlock()
+		}
+	}
+}
+
+func containsPool(pools []Pool, pool Pool) bool {
+	for _, p := range pools {
+		if p == pool {
+			return true
+		}
+	}
+	return false
+}
+
+// lockWithPools locks the current pool of the cpu together with other pools, then the cpu itself. the cpu can be
+// moved while its pool is not locked yet, in which case locking is retried with the new pool
+func (cpu *cpuImpl) lockWithPools(pools ...Pool) func() {
+	for {
+		pool := cpu.getPool()
+		unlockPools := lockPools(append([]Pool{pool}, pools...)...)
+		cpu.mutex.Lock()
+		if cpu.pool == pool {
+			return func() {
+				cpu.mutex.Unlock()
+				unlockPools()
+			}
+		}
+		cpu.mutex.Unlock()
+		unlockPools()
+	}
+}
@@ -0,0 +1,152 @@
+package power
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// records the order in which mutexes are acquired
+type orderedMutex struct {
+	sync.Mutex
+	name  string
+	order *[]string
+}
+
+func (m *orderedMutex) Lock() {
+	m.Mutex.Lock()
+	*m.order = append(*m.order, m.name)
+}
+
+func TestLockPools(t *testing.T) {
+	var order []string
+	newMutex := func(name string) *orderedMutex { return &orderedMutex{name: name, order: &order} }
+	reserved := &reservedPoolType{poolImpl{name: reservedPoolName, mutex: newMutex("reserved")}}
+	shared := &sharedPoolType{poolImpl{name: sharedPoolName, mutex: newMutex("shared")}}
+	exclusiveB := &exclusivePoolType{poolImpl{name: "b", mutex: newMutex("b")}}
+	exclusiveA := &exclusivePoolType{poolImpl{name: "a", mutex: newMutex("a")}}
+
+	unlock := lockPools(exclusiveB, shared, nil, exclusiveA, reserved, shared)
+	assert.Equal(t, []string{"reserved", "shared", "a", "b"}, order)
+	unlock()
+	for _, pool := range []Pool{reserved, shared, exclusiveA, exclusiveB} {
+		mutex := pool.poolMutex().(*orderedMutex)
+		assert.True(t, mutex.TryLock(), pool.Name())
+	}
+}
+
+func TestCpuImpl_lockWithPools(t *testing.T) {
+	shared := &sharedPoolType{poolImpl{name: sharedPoolName, mutex: &sync.Mutex{}}}
+	exclusive := &exclusivePoolType{poolImpl{name: "excl", mutex: &sync.Mutex{}}}
+	cpu := &cpuImpl{id: 0, mutex: &sync.Mutex{}, pool: exclusive}
+
+	// cpu is moved while the locker waits for its pool
+	exclusive.mutex.Lock()
+	locked := make(chan struct{})
+	go func() {
+		unlock := cpu.lockWithPools()
+		assert.True(t, cpu.pool == shared)
+		unlock()
+		close(locked)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cpu.mutex.Lock()
+	cpu.pool = shared
+	cpu.mutex.Unlock()
+	exclusive.mutex.Unlock()
+	<-locked
+	assert.True(t, shared.mutex.(*sync.Mutex).TryLock())
+	assert.True(t, exclusive.mutex.(*sync.Mutex).TryLock())
+	assert.True(t, cpu.mutex.(*sync.Mutex).TryLock())
+}
+
+// run with -race, concurrent operations on pools must neither deadlock nor race
+func TestPoolOperationsStress(t *testing.T) {
+	host, teardown := setupAllocatorTest()
+	defer teardown()
+	defer func(workers uint) { consolidationWorkers = workers }(consolidationWorkers)
+	consolidationWorkers = 4
+	featureList[CStatesFeature].err = nil
+	defer func() { featureList[CStatesFeature].err = uninitialisedErr }()
+
+	excl1, err := host.AddExclusivePool("excl1")
+	assert.NoError(t, err)
+	excl2, err := host.AddExclusivePool("excl2")
+	assert.NoError(t, err)
+	shared := host.GetSharedPool()
+	reserved := host.GetReservedPool()
+	allCpus := *host.GetAllCpus()
+	cpus := func(ids ...uint) CpuList {
+		list, err := allCpus.ManyByIDs(ids)
+		if err != nil {
+			panic(err)
+		}
+		return list
+	}
+
+	const iterations = 50
+	operations := []func(){
+		// opposite moves between shared and exclusive pools
+		func() {
+			_ = excl1.MoveCpus(cpus(0, 1, 2, 3))
+			_ = shared.MoveCpus(cpus(3, 2, 1, 0))
+		},
+		func() {
+			_ = excl2.MoveCpus(cpus(2, 3, 4, 5))
+			_ = excl2.SetCpus(CpuList{})
+		},
+		func() {
+			_ = reserved.MoveCpus(cpus(8, 9))
+			_ = shared.SetCpus(allCpus)
+		},
+		func() {
+			_ = excl1.SetPowerProfile(&profileImpl{name: "excl1"})
+			_ = shared.SetPowerProfile(&profileImpl{name: "shared"})
+		},
+		func() {
+			_ = excl2.SetCStates(CStates{})
+			_ = shared.SetCStates(CStates{})
+		},
+		func() {
+			for _, cpu := range cpus(0, 4, 8) {
+				_ = cpu.SetCStates(CStates{})
+			}
+		},
+	}
+	wg := sync.WaitGroup{}
+	for _, operation := range operations {
+		wg.Add(1)
+		go func(operation func()) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				operation()
+			}
+		}(operation)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Minute):
+		t.Fatal("pool operations deadlocked")
+	}
+
+	// every cpu is in exactly the pool it points to
+	pools := []Pool{reserved, shared, excl1, excl2}
+	for _, cpu := range allCpus {
+		inPools := 0
+		for _, pool := range pools {
+			if pool.Cpus().Contains(cpu) {
+				inPools++
+				assert.True(t, cpu.getPool() == pool, fmt.Sprint("cpu ", cpu.GetID()))
+			}
+		}
+		assert.Equal(t, 1, inPools, fmt.Sprint("cpu ", cpu.GetID()))
+	}
+}
@@ -0,0 +1,124 @@
+package power
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+const (
+	pmQosResumeLatencyFile = "power/pm_qos_resume_latency_us"
+	// "0" written to the resume latency file removes the constraint, "n/a" requests 0 latency
+	pmQosNoConstraint = "0"
+	pmQosZeroLatency  = "n/a"
+)
+
+// defined as var so it can be replaced by unit tests
+var cpuDmaLatencyPath = "/dev/cpu_dma_latency"
+
+// LatencyLimit is the maximum wake-up latency tolerated by CPUs of a pool, the kernel selects idle states accordingly
+type LatencyLimit struct {
+	// applied per CPU via pm_qos_resume_latency_us, 0 only allows polling
+	ResumeLatencyUs uint
+	// additionally hold a system wide /dev/cpu_dma_latency request for as long as the limit is set,
+	// the kernel applies the lowest of all held requests to every CPU
+	Global bool
+}
+
+func initPMQoS() featureStatus {
+	feature := featureStatus{
+		name:     "PM QoS",
+		driver:   "pm_qos",
+		initFunc: initPMQoS,
+		details:  pmQosDetails,
+	}
+	if _, err := os.Stat(filepath.Join(basePath, "cpu0", pmQosResumeLatencyFile)); err != nil {
+		feature.err = fmt.Errorf("cpu resume latency constraints not available: %w", err)
+	}
+	return feature
+}
+
+func pmQosDetails() featureDetails {
+	return featureDetails{paths: []string{allCpusPath(pmQosResumeLatencyFile), cpuDmaLatencyPath}}
+}
+
+// SetLatencyLimit applies the latency limit to all CPUs of the pool, nil removes the limit
+func (pool *poolImpl) SetLatencyLimit(limit *LatencyLimit) error {
+	if !IsFeatureSupported(PMQoSFeature) {
+		return featureList.getFeatureIdError(PMQoSFeature)
+	}
+	if limit != nil {
+		if limit.ResumeLatencyUs > math.MaxInt32 {
+			return &OutOfRangeError{Setting: "latency limit", Value: limit.ResumeLatencyUs, Min: 0, Max: math.MaxInt32}
+		}
+		limitCopy := *limit
+		limit = &limitCopy
+	}
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	if err := pool.updateGlobalLatency(limit); err != nil {
+		return err
+	}
+	pool.latencyLimit = limit
+	if err := consolidateCpus(pool.cpus); err != nil {
+		return fmt.Errorf("failed to apply latency limit: %w", err)
+	}
+	return nil
+}
+
+func (pool *poolImpl) GetLatencyLimit() *LatencyLimit {
+	return pool.latencyLimit
+}
+
+// the request is held for as long as the file stays open, writing to the same file updates it
+func (pool *poolImpl) updateGlobalLatency(limit *LatencyLimit) error {
+	if limit == nil || !limit.Global {
+		return pool.releaseGlobalLatency()
+	}
+	if pool.globalLatency == nil {
+		file, err := os.OpenFile(cpuDmaLatencyPath, os.O_WRONLY, 0)
+		if err != nil {
+			return fmt.Errorf("failed to request global latency limit: %w", err)
+		}
+		pool.globalLatency = file
+	}
+	if err := binary.Write(pool.globalLatency, binary.NativeEndian, int32(limit.ResumeLatencyUs)); err != nil {
+		return fmt.Errorf("failed to request global latency limit: %w", err)
+	}
+	log.Info("holding global latency limit", "pool", pool.name, "latency us", limit.ResumeLatencyUs)
+	return nil
+}
+
+func (pool *poolImpl) releaseGlobalLatency() error {
+	if pool.globalLatency == nil {
+		return nil
+	}
+	log.Info("releasing global latency limit", "pool", pool.name)
+	err := pool.globalLatency.Close()
+	pool.globalLatency = nil
+	if err != nil {
+		return fmt.Errorf("failed to release global latency limit: %w", err)
+	}
+	return nil
+}
+
+func (cpu *cpuImpl) updateLatencyLimit() error {
+	if !IsFeatureSupported(PMQoSFeature) {
+		return nil
+	}
+	value := pmQosNoConstraint
+	if limit := cpu.pool.GetLatencyLimit(); limit != nil {
+		value = fmt.Sprint(limit.ResumeLatencyUs)
+		if limit.ResumeLatencyUs == 0 {
+			value = pmQosZeroLatency
+		}
+	}
+	latencyFile := filepath.Join(basePath, fmt.Sprint("cpu", cpu.id), pmQosResumeLatencyFile)
+	if err := writeSysfsValue(latencyFile, value); err != nil {
+		return fmt.Errorf("could not apply latency limit on cpu %d: %w", cpu.id, err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package power
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupPMQoSTests(numCpus uint) func() {
+	origBasePath := basePath
+	basePath = "testing/cpus"
+	// fake files are recreated, cached values of previous tests do not apply
+	sysfsWrites.reset(false)
+	origDmaLatencyPath := cpuDmaLatencyPath
+	cpuDmaLatencyPath = filepath.Join(basePath, "cpu_dma_latency")
+
+	featureList[PMQoSFeature].err = nil
+	if err := os.MkdirAll(basePath, os.ModePerm); err != nil {
+		panic(err)
+	}
+	for i := uint(0); i < numCpus; i++ {
+		powerDir := filepath.Join(basePath, fmt.Sprint("cpu", i), "power")
+		if err := os.MkdirAll(powerDir, os.ModePerm); err != nil {
+			panic(err)
+		}
+		if err := os.WriteFile(filepath.Join(powerDir, "pm_qos_resume_latency_us"), []byte("0"), 0644); err != nil {
+			panic(err)
+		}
+	}
+	if err := os.WriteFile(cpuDmaLatencyPath, []byte{}, 0644); err != nil {
+		panic(err)
+	}
+	return func() {
+		if err := os.RemoveAll(strings.Split(basePath, "/")[0]); err != nil {
+			panic(err)
+		}
+		basePath = origBasePath
+		cpuDmaLatencyPath = origDmaLatencyPath
+		featureList[PMQoSFeature].err = uninitialisedErr
+	}
+}
+
+func readResumeLatency(cpuID uint) string {
+	value, _ := readCpuStringProperty(cpuID, pmQosResumeLatencyFile)
+	return value
+}
+
+// returns the last request written to the fake cpu_dma_latency file
+func readGlobalLatency() (int32, int) {
+	content, _ := os.ReadFile(cpuDmaLatencyPath)
+	if len(content) < 4 {
+		return -1, len(content)
+	}
+	return int32(binary.NativeEndian.Uint32(content[len(content)-4:])), len(content)
+}
+
+func Test_initPMQoS(t *testing.T) {
+	teardown := setupPMQoSTests(1)
+	assert.NoError(t, initPMQoS().err)
+	teardown()
+
+	teardown = setupPMQoSTests(0)
+	defer teardown()
+	feature := initPMQoS()
+	assert.ErrorContains(t, feature.err, "not available")
+	assert.Equal(t, "PM QoS", feature.name)
+}
+
+func TestPoolImpl_SetLatencyLimit(t *testing.T) {
+	defer setupPMQoSTests(2)()
+	pool := &poolImpl{name: "pool", mutex: &sync.Mutex{}}
+	for i := uint(0); i < 2; i++ {
+		cpu := &cpuImpl{id: i, mutex: &sync.Mutex{}, pool: pool}
+		pool.cpus.add(cpu)
+	}
+
+	assert.NoError(t, pool.SetLatencyLimit(&LatencyLimit{ResumeLatencyUs: 20}))
+	assert.Equal(t, "20", readResumeLatency(0))
+	assert.Equal(t, "20", readResumeLatency(1))
+	assert.Equal(t, &LatencyLimit{ResumeLatencyUs: 20}, pool.GetLatencyLimit())
+	assert.Nil(t, pool.globalLatency)
+
+	// zero latency has to be requested with n/a
+	assert.NoError(t, pool.SetLatencyLimit(&LatencyLimit{}))
+	assert.Equal(t, pmQosZeroLatency, readResumeLatency(0))
+
+	// global request is held and updated
+	assert.NoError(t, pool.SetLatencyLimit(&LatencyLimit{ResumeLatencyUs: 20, Global: true}))
+	assert.NotNil(t, pool.globalLatency)
+	value, _ := readGlobalLatency()
+	assert.Equal(t, int32(20), value)
+	assert.NoError(t, pool.SetLatencyLimit(&LatencyLimit{ResumeLatencyUs: 10, Global: true}))
+	value, size := readGlobalLatency()
+	assert.Equal(t, int32(10), value)
+	assert.Equal(t, 8, size)
+
+	// removing the limit releases the global request
+	assert.NoError(t, pool.SetLatencyLimit(nil))
+	assert.Nil(t, pool.globalLatency)
+	assert.Nil(t, pool.GetLatencyLimit())
+	assert.Equal(t, pmQosNoConstraint, readResumeLatency(1))
+
+	assert.Error(t, pool.SetLatencyLimit(&LatencyLimit{ResumeLatencyUs: 1 << 31}))
+
+	// global request cannot be opened
+	cpuDmaLatencyPath = "not existing"
+	assert.ErrorContains(t, pool.SetLatencyLimit(&LatencyLimit{Global: true}), "failed to request global latency limit")
+
+	featureList[PMQoSFeature].err = fmt.Errorf("not supported")
+	assert.ErrorIs(t, pool.SetLatencyLimit(nil), featureList[PMQoSFeature].err)
+}
+
+func TestCpuImpl_updateLatencyLimit(t *testing.T) {
+	defer setupPMQoSTests(1)()
+	pool := new(poolMock)
+	cpu := &cpuImpl{id: 0, pool: pool}
+
+	pool.On("GetLatencyLimit").Return(&LatencyLimit{ResumeLatencyUs: 5}).Once()
+	assert.NoError(t, cpu.updateLatencyLimit())
+	assert.Equal(t, "5", readResumeLatency(0))
+
+	pool.On("GetLatencyLimit").Return(nil).Once()
+	assert.NoError(t, cpu.updateLatencyLimit())
+	assert.Equal(t, pmQosNoConstraint, readResumeLatency(0))
+
+	cpu.id = 3
+	pool.On("GetLatencyLimit").Return(nil).Once()
+	assert.ErrorContains(t, cpu.updateLatencyLimit(), "could not apply latency limit on cpu 3")
+
+	// nothing is written if the feature is not supported
+	featureList[PMQoSFeature].err = fmt.Errorf("not supported")
+	assert.NoError(t, cpu.updateLatencyLimit())
+	pool.AssertExpectations(t)
+}
+
+func TestExclusivePoolType_RemoveReleasesLatency(t *testing.T) {
+	defer setupPMQoSTests(0)()
+	host := new(hostMock)
+	host.On("GetAllCpus").Return(new(CpuList))
+	pool := &exclusivePoolType{poolImpl{host: host, mutex: &sync.Mutex{}}}
+	host.On("removeExclusivePool", pool).Return(nil)
+
+	assert.NoError(t, pool.SetLatencyLimit(&LatencyLimit{ResumeLatencyUs: 20, Global: true}))
+	assert.NotNil(t, pool.globalLatency)
+	assert.NoError(t, pool.Remove())
+	assert.Nil(t, pool.globalLatency)
+}
@@ -1,7 +1,9 @@
 package power
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"sync"
 )
 
@@ -14,6 +16,11 @@ type poolImpl struct {
 	PowerProfile Profile
 	// C-States
 	CStatesProfile *CStates
+	// Uncore
+	uncore Uncore
+	// PM QoS
+	latencyLimit  *LatencyLimit
+	globalLatency *os.File
 }
 
 type Pool interface {
@@ -32,11 +39,26 @@ type Pool interface {
 	SetPowerProfile(profile Profile) error
 	GetPowerProfile() Profile
 
+	// variants checking ctx between cpus, interrupted operations return ProgressError. cpus moved before
+	// the interruption stay moved, pool settings are rolled back
+	SetCpusContext(ctx context.Context, requestedCpus CpuList) error
+	MoveCpusContext(ctx context.Context, cpus CpuList) error
+	SetPowerProfileContext(ctx context.Context, profile Profile) error
+	SetCStatesContext(ctx context.Context, states CStates, opts ...CStatesOption) error
+	SetUncoreContext(ctx context.Context, uncore Uncore) error
+
 	poolMutex() sync.Locker
 
 	// c-states
-	SetCStates(states CStates) error
+	SetCStates(states CStates, opts ...CStatesOption) error
 	getCStates() *CStates
+	// uncore
+	SetUncore(uncore Uncore) error
+	GetUncore() Uncore
+	reconcileUncore() error
+	// pm qos
+	SetLatencyLimit(limit *LatencyLimit) error
+	GetLatencyLimit() *LatencyLimit
 	// private interface members
 	getHost() Host
 	isExclusive() bool
@@ -67,6 +89,14 @@ func (pool *poolImpl) MoveCpuIDs(cpuIDs []uint) error {
 	panic("virtual")
 }
 
+func (pool *poolImpl) SetCpusContext(context.Context, CpuList) error {
+	panic("virtual")
+}
+
+func (pool *poolImpl) MoveCpusContext(context.Context, CpuList) error {
+	panic("virtual")
+}
+
 func (pool *poolImpl) Remove() error {
 	panic("'virtual' function")
 } // virtual
@@ -80,20 +110,19 @@ func (pool *poolImpl) poolMutex() sync.Locker {
 }
 
 func (pool *poolImpl) SetPowerProfile(profile Profile) error {
+	return pool.SetPowerProfileContext(context.Background(), profile)
+}
+
+func (pool *poolImpl) SetPowerProfileContext(ctx context.Context, profile Profile) error {
 	log.V(4).Info("SetPowerProfile mutex lock", "pool", pool.name)
 	pool.mutex.Lock()
+	previous := pool.PowerProfile
 	pool.PowerProfile = profile
 	defer func() {
 		pool.mutex.Unlock()
 		log.V(4).Info("SetPowerProfile mutex unlock", "pool", pool.name)
 	}()
-	for _, cpu := range pool.cpus {
-		err := cpu.consolidate()
-		if err != nil {
-			return err
-		}
-	}
-	return nil
+	return pool.consolidateContext(ctx, "set power profile", func() { pool.PowerProfile = previous })
 }
 
 func (pool *poolImpl) GetPowerProfile() Profile {
@@ -120,12 +149,10 @@ func (sharedPool *sharedPoolType) MoveCpuIDs(cpuIDs []uint) error {
 	return sharedPool.MoveCpus(cpus)
 }
 func (sharedPool *sharedPoolType) MoveCpus(cpus CpuList) error {
-	for _, cpu := range cpus {
-		if err := cpu.SetPool(sharedPool); err != nil {
-			return err
-		}
-	}
-	return nil
+	return sharedPool.MoveCpusContext(context.Background(), cpus)
+}
+func (sharedPool *sharedPoolType) MoveCpusContext(ctx context.Context, cpus CpuList) error {
+	return moveCpus(ctx, sharedPool, cpus)
 }
 func (sharedPool *sharedPoolType) SetCpuIDs(cpuIDs []uint) error {
 	cores, err := sharedPool.host.GetAllCpus().ManyByIDs(cpuIDs)
@@ -138,15 +165,23 @@ func (sharedPool *sharedPoolType) SetCpuIDs(cpuIDs []uint) error {
 // SetCpus on shared pool with place all desired cpus in shared pool
 // undesired cpus that were in the shared pool will be placed in the reserved pool
 func (sharedPool *sharedPoolType) SetCpus(requestedCores CpuList) error {
-	for _, cpu := range *sharedPool.host.GetAllCpus() {
+	return sharedPool.SetCpusContext(context.Background(), requestedCores)
+}
+func (sharedPool *sharedPoolType) SetCpusContext(ctx context.Context, requestedCores CpuList) error {
+	allCpus := *sharedPool.host.GetAllCpus()
+	changes := newPoolChanges("set cpus of pool " + sharedPool.name)
+	for i, cpu := range allCpus {
+		if err := changes.interrupted(ctx, pendingCpus(requestedCores, allCpus[i:])); err != nil {
+			return err
+		}
 		if requestedCores.Contains(cpu) {
-			err := cpu.SetPool(sharedPool)
+			err := changes.setPool(cpu, sharedPool)
 			if err != nil {
 				return err
 			}
 		} else {
 			if cpu.getPool() == sharedPool { // move cpus we don't want if the shared pool to reserved, don't touch any exclusive
-				err := cpu.SetPool(sharedPool.host.GetReservedPool())
+				err := changes.setPool(cpu, sharedPool.host.GetReservedPool())
 				if err != nil {
 					return err
 				}
@@ -175,12 +210,10 @@ func (reservedPool *reservedPoolType) MoveCpuIDs(cpuIDs []uint) error {
 	return reservedPool.MoveCpus(cpus)
 }
 func (reservedPool *reservedPoolType) MoveCpus(cpus CpuList) error {
-	for _, cpu := range cpus {
-		if err := cpu.SetPool(reservedPool); err != nil {
-			return err
-		}
-	}
-	return nil
+	return reservedPool.MoveCpusContext(context.Background(), cpus)
+}
+func (reservedPool *reservedPoolType) MoveCpusContext(ctx context.Context, cpus CpuList) error {
+	return moveCpus(ctx, reservedPool, cpus)
 }
 func (reservedPool *reservedPoolType) SetCpuIDs(cpuIDs []uint) error {
 	cpus, err := reservedPool.host.GetAllCpus().ManyByIDs(cpuIDs)
@@ -192,8 +225,14 @@ func (reservedPool *reservedPoolType) SetCpuIDs(cpuIDs []uint) error {
 func (reservedPool *reservedPoolType) SetPowerProfile(Profile) error {
 	return fmt.Errorf("cannot set power profile for reserved pool")
 }
+func (reservedPool *reservedPoolType) SetPowerProfileContext(context.Context, Profile) error {
+	return reservedPool.SetPowerProfile(nil)
+}
 
 func (reservedPool *reservedPoolType) SetCpus(cores CpuList) error {
+	return reservedPool.SetCpusContext(context.Background(), cores)
+}
+func (reservedPool *reservedPoolType) SetCpusContext(ctx context.Context, cores CpuList) error {
 	/*
 		case 1: cpu in any exclusive pool, not passed matching IDs -> untouched
 		case 2: cpu in any exclusive pool, matching passed IDs -> error
@@ -206,19 +245,28 @@ func (reservedPool *reservedPoolType) SetCpus(cores CpuList) error {
 	*/
 
 	sharedPool := reservedPool.host.GetSharedPool()
-
-	for _, cpu := range *reservedPool.host.GetAllCpus() {
+	allCpus := *reservedPool.host.GetAllCpus()
+	changes := newPoolChanges("set cpus of pool " + reservedPool.name)
+	for i, cpu := range allCpus {
+		if err := changes.interrupted(ctx, pendingCpus(cores, allCpus[i:])); err != nil {
+			return err
+		}
 		if cores.Contains(cpu) { // case 2,4, 6
 			if cpu.getPool().isExclusive() { // case 2
-				return fmt.Errorf("cpus cannot be moved directly from exclusive to reserved pool")
+				return &PoolTransitionError{
+					CpuID:  cpu.GetID(),
+					From:   cpu.getPool().Name(),
+					To:     reservedPool.name,
+					Reason: "cpus cannot be moved directly from exclusive to reserved pool",
+				}
 			}
-			err := cpu.SetPool(reservedPool) // case 4
+			err := changes.setPool(cpu, reservedPool) // case 4
 			if err != nil {
 				return err
 			}
 		} else { // case 1,3,5
 			if cpu.getPool() == reservedPool { // case 5
-				err := cpu.SetPool(sharedPool)
+				err := changes.setPool(cpu, sharedPool)
 				if err != nil {
 					return err
 				}
@@ -249,12 +297,10 @@ func (pool *exclusivePoolType) MoveCpuIDs(cpuIDs []uint) error {
 	return pool.MoveCpus(cpus)
 }
 func (pool *exclusivePoolType) MoveCpus(cpus CpuList) error {
-	for _, cpu := range cpus {
-		if err := cpu.SetPool(pool); err != nil {
-			return err
-		}
-	}
-	return nil
+	return pool.MoveCpusContext(context.Background(), cpus)
+}
+func (pool *exclusivePoolType) MoveCpusContext(ctx context.Context, cpus CpuList) error {
+	return moveCpus(ctx, pool, cpus)
 }
 func (pool *exclusivePoolType) SetCpuIDs(cpuIDs []uint) error {
 	cpus, err := pool.host.GetAllCpus().ManyByIDs(cpuIDs)
@@ -265,9 +311,17 @@ func (pool *exclusivePoolType) SetCpuIDs(cpuIDs []uint) error {
 }
 
 func (pool *exclusivePoolType) SetCpus(requestedCores CpuList) error {
-	for _, cpu := range *pool.host.GetAllCpus() {
+	return pool.SetCpusContext(context.Background(), requestedCores)
+}
+func (pool *exclusivePoolType) SetCpusContext(ctx context.Context, requestedCores CpuList) error {
+	allCpus := *pool.host.GetAllCpus()
+	changes := newPoolChanges("set cpus of pool " + pool.name)
+	for i, cpu := range allCpus {
+		if err := changes.interrupted(ctx, pendingCpus(requestedCores, allCpus[i:])); err != nil {
+			return err
+		}
 		if requestedCores.Contains(cpu) {
-			err := cpu.SetPool(pool)
+			err := changes.setPool(cpu, pool)
 			if err != nil {
 				return err
 			}
@@ -275,7 +329,7 @@ func (pool *exclusivePoolType) SetCpus(requestedCores CpuList) error {
 			if cpu.getPool() != pool {
 				continue
 			}
-			err := cpu.SetPool(pool.host.GetSharedPool())
+			err := changes.setPool(cpu, pool.host.GetSharedPool())
 			if err != nil {
 				return err
 			}
@@ -292,7 +346,10 @@ func (pool *exclusivePoolType) Remove() error {
 	if err := pool.Clear(); err != nil {
 		return err
 	}
-	if err := pool.host.GetAllExclusivePools().remove(pool); err != nil {
+	if err := pool.host.removeExclusivePool(pool); err != nil {
+		return err
+	}
+	if err := pool.releaseGlobalLatency(); err != nil {
 		return err
 	}
 	// improvement: mark current pool as invalid
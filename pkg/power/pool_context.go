@@ -0,0 +1,112 @@
+package power
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ProgressError is returned by context variants of pool operations when the context is done before the operation
+// finished. errors.Is(err, context.Canceled) or context.DeadlineExceeded can be used to detect it
+type ProgressError struct {
+	// name of the interrupted operation
+	Op string
+	// cpus the operation remains applied to, for moves only cpus whose pool changed
+	Applied CpuList
+	// cpus the operation was not applied to, for moves only requested cpus not handled yet
+	Pending CpuList
+	// cpus changed before the interruption were reverted to the previous setting
+	RolledBack bool
+	// context error, joined with errors of the rollback if it failed
+	Err error
+}
+
+func (e *ProgressError) Error() string {
+	msg := fmt.Sprintf("%s interrupted: applied to cpus %v, pending cpus %v", e.Op, e.Applied.IDs(), e.Pending.IDs())
+	if e.RolledBack {
+		msg += ", rolled back"
+	}
+	return fmt.Sprintf("%s: %v", msg, e.Err)
+}
+
+func (e *ProgressError) Unwrap() error {
+	return e.Err
+}
+
+// poolChanges records cpus whose pool changed during an operation moving cpus, reported as applied if ctx is done
+type poolChanges struct {
+	op      string
+	applied CpuList
+}
+
+func newPoolChanges(op string) *poolChanges {
+	return &poolChanges{op: op, applied: CpuList{}}
+}
+
+// interrupted returns ProgressError if ctx is done, pending are the requested cpus not handled yet
+func (c *poolChanges) interrupted(ctx context.Context, pending CpuList) error {
+	if err := ctx.Err(); err != nil {
+		return &ProgressError{Op: c.op, Applied: c.applied, Pending: pending, Err: err}
+	}
+	return nil
+}
+
+// setPool moves the cpu to the pool, recording it if it was in another pool
+func (c *poolChanges) setPool(cpu Cpu, pool Pool) error {
+	previous := cpu.getPool()
+	if err := cpu.SetPool(pool); err != nil {
+		return err
+	}
+	if previous != pool {
+		c.applied = append(c.applied, cpu)
+	}
+	return nil
+}
+
+// requested cpus among the cpus not handled yet
+func pendingCpus(requested CpuList, remaining CpuList) CpuList {
+	pending := CpuList{}
+	for _, cpu := range remaining {
+		if requested.Contains(cpu) {
+			pending = append(pending, cpu)
+		}
+	}
+	return pending
+}
+
+// moveCpus moves cpus to a pool one by one, cpus moved before ctx is done stay in the pool
+func moveCpus(ctx context.Context, pool Pool, cpus CpuList) error {
+	changes := newPoolChanges("move cpus to pool " + pool.Name())
+	for i, cpu := range cpus {
+		if err := changes.interrupted(ctx, cpus[i:]); err != nil {
+			return err
+		}
+		if err := changes.setPool(cpu, pool); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// consolidateContext consolidates cpus of the pool after one of its settings changed, caller holds the pool mutex.
+// if ctx is done before every cpu was consolidated, restore reverts the setting and cpus consolidated so far are
+// consolidated again so the pool stays consistent
+func (pool *poolImpl) consolidateContext(ctx context.Context, op string, restore func()) error {
+	consolidated, err := consolidateCpusContext(ctx, pool.cpus)
+	ctxErr := ctx.Err()
+	if ctxErr == nil || len(consolidated) == len(pool.cpus) {
+		return err
+	}
+	restore()
+	_, rollbackErr := consolidateCpusContext(context.Background(), consolidated)
+	progress := &ProgressError{Op: op, Applied: CpuList{}, RolledBack: rollbackErr == nil, Err: errors.Join(ctxErr, err, rollbackErr)}
+	if !progress.RolledBack {
+		progress.Applied = consolidated
+	}
+	for _, cpu := range pool.cpus {
+		if !progress.Applied.Contains(cpu) {
+			progress.Pending = append(progress.Pending, cpu)
+		}
+	}
+	return progress
+}
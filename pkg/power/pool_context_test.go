@@ -0,0 +1,208 @@
+package power
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestProgressError(t *testing.T) {
+	err := &ProgressError{
+		Op:      "set power profile",
+		Applied: CpuList{&cpuImpl{id: 1}},
+		Pending: CpuList{&cpuImpl{id: 2}, &cpuImpl{id: 3}},
+		Err:     context.DeadlineExceeded,
+	}
+	assert.EqualError(t, err, "set power profile interrupted: applied to cpus [1], pending cpus [2 3]: context deadline exceeded")
+	assert.ErrorIs(t, fmt.Errorf("wrapped: %w", err), context.DeadlineExceeded)
+
+	err.RolledBack = true
+	assert.Contains(t, err.Error(), ", rolled back:")
+}
+
+func TestMoveCpusContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool := &exclusivePoolType{poolImpl{name: "excl"}}
+	cpu1 := new(cpuMock)
+	cpu1.On("GetID").Return(uint(1))
+	cpu1.On("SetPool", pool).Return(nil).Run(func(mock.Arguments) { cancel() })
+	cpu1.On("getPool").Return(&sharedPoolType{})
+	cpu2 := new(cpuMock)
+	cpu2.On("GetID").Return(uint(2))
+
+	err := pool.MoveCpusContext(ctx, CpuList{cpu1, cpu2})
+	var progress *ProgressError
+	assert.ErrorAs(t, err, &progress)
+	assert.ErrorIs(t, err, context.Canceled)
+	// moved cpus stay moved
+	assert.Equal(t, CpuList{cpu1}, progress.Applied)
+	assert.Equal(t, CpuList{cpu2}, progress.Pending)
+	assert.False(t, progress.RolledBack)
+	cpu2.AssertNotCalled(t, "SetPool", mock.Anything)
+}
+
+func TestExclusivePoolType_SetCpusContext(t *testing.T) {
+	host := new(hostMock)
+	pool := &exclusivePoolType{poolImpl{name: "excl", host: host}}
+	cpus := CpuList{new(cpuMock), new(cpuMock)}
+	host.On("GetAllCpus").Return(&cpus)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := pool.SetCpusContext(ctx, cpus)
+	var progress *ProgressError
+	assert.ErrorAs(t, err, &progress)
+	assert.Empty(t, progress.Applied)
+	assert.Equal(t, cpus, progress.Pending)
+}
+
+func TestExclusivePoolType_SetCpusContextProgress(t *testing.T) {
+	host, teardown := setupAllocatorTest()
+	defer teardown()
+	other, err := host.AddExclusivePool("other")
+	assert.NoError(t, err)
+	assert.NoError(t, other.MoveCpuIDs([]uint{0}))
+	pool, err := host.AddExclusivePool("excl")
+	assert.NoError(t, err)
+	assert.NoError(t, pool.MoveCpuIDs([]uint{1}))
+	requested, err := host.GetAllCpus().ManyByIDs([]uint{1, 2, 3})
+	assert.NoError(t, err)
+
+	// interrupted before cpu 3, cpu 0 of another pool and cpu 1 already in the pool did not change
+	err = pool.SetCpusContext(&doneAfterContext{Context: context.Background(), checks: 3}, requested)
+	var progress *ProgressError
+	assert.ErrorAs(t, err, &progress)
+	assert.Equal(t, []uint{2}, progress.Applied.IDs())
+	assert.Equal(t, []uint{3}, progress.Pending.IDs())
+	assert.ElementsMatch(t, []uint{1, 2}, pool.Cpus().IDs())
+}
+
+func TestPoolImpl_SetPowerProfileContext(t *testing.T) {
+	defer func(workers uint) { consolidationWorkers = workers }(consolidationWorkers)
+	consolidationWorkers = 1
+	previous := &profileImpl{name: "previous"}
+	pool := &poolImpl{name: "pool", mutex: &sync.Mutex{}, PowerProfile: previous}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cpu1 := new(cpuMock)
+	cpu1.On("GetID").Return(uint(1))
+	// first consolidation applies the new profile and is interrupted, second one rolls back
+	cpu1.On("consolidate").Return(nil).Run(func(mock.Arguments) { cancel() })
+	cpu2 := new(cpuMock)
+	cpu2.On("GetID").Return(uint(2))
+	pool.cpus = CpuList{cpu1, cpu2}
+
+	err := pool.SetPowerProfileContext(ctx, &profileImpl{name: "new"})
+	var progress *ProgressError
+	assert.ErrorAs(t, err, &progress)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, progress.RolledBack)
+	assert.Empty(t, progress.Applied)
+	assert.Equal(t, CpuList{cpu1, cpu2}, progress.Pending)
+	assert.Equal(t, previous, pool.GetPowerProfile())
+	cpu1.AssertNumberOfCalls(t, "consolidate", 2)
+	cpu2.AssertNotCalled(t, "consolidate")
+
+	// failed rollback leaves the setting applied where it was consolidated
+	ctx, cancel = context.WithCancel(context.Background())
+	cpu1 = new(cpuMock)
+	cpu1.On("GetID").Return(uint(1))
+	cpu1.On("consolidate").Return(nil).Once().Run(func(mock.Arguments) { cancel() })
+	cpu1.On("consolidate").Return(fmt.Errorf("rollback failed")).Once()
+	pool.cpus = CpuList{cpu1, cpu2}
+	err = pool.SetPowerProfileContext(ctx, &profileImpl{name: "new"})
+	assert.ErrorAs(t, err, &progress)
+	assert.False(t, progress.RolledBack)
+	assert.Equal(t, CpuList{cpu1}, progress.Applied)
+	assert.Equal(t, CpuList{cpu2}, progress.Pending)
+	assert.ErrorContains(t, err, "rollback failed")
+
+	// not interrupted
+	cpu1 = new(cpuMock)
+	cpu1.On("consolidate").Return(nil)
+	pool.cpus = CpuList{cpu1}
+	assert.NoError(t, pool.SetPowerProfileContext(context.Background(), &profileImpl{name: "new"}))
+	assert.Equal(t, "new", pool.GetPowerProfile().Name())
+}
+
+func TestPoolImpl_SetCStatesContext(t *testing.T) {
+	defer setupCpuCStatesTests(nil)()
+	cStatesNamesMap = map[string]int{"C0": 0}
+	previous := &CStates{"C0": false}
+	cpu := new(cpuMock)
+	cpu.On("GetID").Return(uint(0))
+	pool := &poolImpl{name: "pool", mutex: &sync.Mutex{}, cpus: CpuList{cpu}, CStatesProfile: previous}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := pool.SetCStatesContext(ctx, CStates{"C0": true})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorContains(t, err, "failed to apply c-states: set c-states interrupted")
+	assert.Equal(t, previous, pool.getCStates())
+	cpu.AssertNotCalled(t, "consolidate")
+}
+
+func TestPoolImpl_SetUncoreContext(t *testing.T) {
+	host, teardown := setupAllocatorTest()
+	defer teardown()
+	uncoreFiles := map[string]map[string]string{}
+	for _, pkgDie := range []string{"package_00_die_00", "package_00_die_01", "package_01_die_00", "package_01_die_01"} {
+		uncoreFiles[pkgDie] = map[string]string{"Max": "2400000", "Min": "1200000", "initMax": "2400000", "initMin": "1200000"}
+	}
+	defer setupUncoreTests(uncoreFiles, "")()
+	defaultUncore.min = 1_200_000
+	defaultUncore.max = 2_400_000
+	domains, err := discoverUncoreDomains()
+	assert.NoError(t, err)
+	host.topology.(*cpuTopology).addUncoreDomains(domains)
+
+	pool, err := host.AddExclusivePool("pool")
+	assert.NoError(t, err)
+	assert.NoError(t, pool.MoveCpuIDs([]uint{0}))
+	uncore := &uncoreFreq{min: 1_600_000, max: 2_000_000}
+	assert.NoError(t, pool.SetUncoreContext(context.Background(), uncore))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = pool.SetUncoreContext(ctx, &uncoreFreq{min: 1_400_000, max: 2_200_000})
+	var progress *ProgressError
+	assert.True(t, errors.As(err, &progress))
+	assert.True(t, progress.RolledBack)
+	assert.Equal(t, uncore, pool.GetUncore())
+	value, _ := readUncoreProperty(0, 0, uncoreMaxFreqFile)
+	assert.Equal(t, uint(2_000_000), value)
+
+	// ctx done right after the last die was reconciled does not revert the completed operation
+	dies := 0
+	for _, pkg := range *host.Topology().Packages() {
+		dies += len(*pkg.Dies())
+	}
+	lateUncore := &uncoreFreq{min: 1_400_000, max: 2_200_000}
+	assert.NoError(t, pool.SetUncoreContext(&doneAfterContext{Context: context.Background(), checks: dies}, lateUncore))
+	assert.Equal(t, lateUncore, pool.GetUncore())
+	value, _ = readUncoreProperty(0, 0, uncoreMaxFreqFile)
+	assert.Equal(t, uint(2_200_000), value)
+
+	assert.Error(t, host.GetReservedPool().SetUncoreContext(context.Background(), uncore))
+}
+
+// doneAfterContext reports deadline exceeded once Err was called more than checks times
+type doneAfterContext struct {
+	context.Context
+	checks int
+}
+
+func (c *doneAfterContext) Err() error {
+	if c.checks == 0 {
+		return context.DeadlineExceeded
+	}
+	c.checks--
+	return nil
+}
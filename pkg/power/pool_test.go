@@ -1,6 +1,7 @@
 package power
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"testing"
@@ -17,7 +18,7 @@ func (m *poolMock) poolMutex() sync.Locker {
 	return m.Called().Get(0).(sync.Locker)
 }
 
-func (m *poolMock) SetCStates(states CStates) error {
+func (m *poolMock) SetCStates(states CStates, opts ...CStatesOption) error {
 	return m.Called(states).Error(0)
 }
 
@@ -29,6 +30,54 @@ func (m *poolMock) getCStates() *CStates {
 	return args.(*CStates)
 }
 
+func (m *poolMock) SetUncore(uncore Uncore) error {
+	return m.Called(uncore).Error(0)
+}
+
+func (m *poolMock) GetUncore() Uncore {
+	args := m.Called().Get(0)
+	if args == nil {
+		return nil
+	}
+	return args.(Uncore)
+}
+
+func (m *poolMock) SetLatencyLimit(limit *LatencyLimit) error {
+	return m.Called(limit).Error(0)
+}
+
+func (m *poolMock) GetLatencyLimit() *LatencyLimit {
+	args := m.Called().Get(0)
+	if args == nil {
+		return nil
+	}
+	return args.(*LatencyLimit)
+}
+
+func (m *poolMock) SetCpusContext(ctx context.Context, cpus CpuList) error {
+	return m.Called(ctx, cpus).Error(0)
+}
+
+func (m *poolMock) MoveCpusContext(ctx context.Context, cpus CpuList) error {
+	return m.Called(ctx, cpus).Error(0)
+}
+
+func (m *poolMock) SetPowerProfileContext(ctx context.Context, profile Profile) error {
+	return m.Called(ctx, profile).Error(0)
+}
+
+func (m *poolMock) SetCStatesContext(ctx context.Context, states CStates, opts ...CStatesOption) error {
+	return m.Called(ctx, states).Error(0)
+}
+
+func (m *poolMock) SetUncoreContext(ctx context.Context, uncore Uncore) error {
+	return m.Called(ctx, uncore).Error(0)
+}
+
+func (m *poolMock) reconcileUncore() error {
+	return m.Called().Error(0)
+}
+
 func (m *poolMock) isExclusive() bool {
 	return m.Called().Bool(0)
 }
@@ -152,7 +201,9 @@ func TestExclusivePoolType_MoveCpus(t *testing.T) {
 	mockCore2 := new(cpuMock)
 	p := new(exclusivePoolType)
 	mockCore.On("SetPool", p).Return(nil)
+	mockCore.On("getPool").Return(nil)
 	mockCore2.On("SetPool", p).Return(nil)
+	mockCore2.On("getPool").Return(nil)
 
 	assert.NoError(t, p.MoveCpus(CpuList{mockCore, mockCore2}))
 
@@ -163,6 +214,7 @@ func TestExclusivePoolType_MoveCpus(t *testing.T) {
 	setPoolErr := fmt.Errorf("")
 	mockCore = new(cpuMock)
 	mockCore.On("SetPool", p).Return(setPoolErr)
+	mockCore.On("getPool").Return(nil)
 
 	assert.ErrorIs(t, p.MoveCpus(CpuList{mockCore}), setPoolErr)
 	mockCore.AssertExpectations(t)
@@ -184,7 +236,9 @@ func TestSharedPoolType_MoveCpus(t *testing.T) {
 	mockCore2 := new(cpuMock)
 	p := new(sharedPoolType)
 	mockCore.On("SetPool", p).Return(nil)
+	mockCore.On("getPool").Return(nil)
 	mockCore2.On("SetPool", p).Return(nil)
+	mockCore2.On("getPool").Return(nil)
 
 	assert.NoError(t, p.MoveCpus(CpuList{mockCore, mockCore2}))
 
@@ -195,6 +249,7 @@ func TestSharedPoolType_MoveCpus(t *testing.T) {
 	setPoolErr := fmt.Errorf("")
 	mockCore = new(cpuMock)
 	mockCore.On("SetPool", p).Return(setPoolErr)
+	mockCore.On("getPool").Return(nil)
 
 	assert.ErrorIs(t, p.MoveCpus(CpuList{mockCore}), setPoolErr)
 	mockCore.AssertExpectations(t)
@@ -216,7 +271,9 @@ func TestReservedPoolType_MoveCpus(t *testing.T) {
 	mockCore2 := new(cpuMock)
 	p := new(reservedPoolType)
 	mockCore.On("SetPool", p).Return(nil)
+	mockCore.On("getPool").Return(nil)
 	mockCore2.On("SetPool", p).Return(nil)
+	mockCore2.On("getPool").Return(nil)
 
 	assert.NoError(t, p.MoveCpus(CpuList{mockCore, mockCore2}))
 
@@ -227,6 +284,7 @@ func TestReservedPoolType_MoveCpus(t *testing.T) {
 	setPoolErr := fmt.Errorf("")
 	mockCore = new(cpuMock)
 	mockCore.On("SetPool", p).Return(setPoolErr)
+	mockCore.On("getPool").Return(nil)
 
 	assert.ErrorIs(t, p.MoveCpus(CpuList{mockCore}), setPoolErr)
 	mockCore.AssertExpectations(t)
@@ -299,6 +357,7 @@ func TestSharedPoolType_SetCores(t *testing.T) {
 		core := new(cpuMock)
 		if i >= 2 && i < 5 {
 			core.On("SetPool", sharedPool).Return(nil)
+			core.On("getPool").Return(reservedPool)
 		} else {
 			core.On("SetPool", reservedPool).Return(nil)
 			core.On("getPool").Return(sharedPool)
@@ -317,6 +376,7 @@ func TestSharedPoolType_SetCores(t *testing.T) {
 	err := fmt.Errorf("borked")
 	allCores[0] = new(cpuMock)
 	allCores[0].(*cpuMock).On("SetPool", mock.Anything).Return(err)
+	allCores[0].(*cpuMock).On("getPool").Return(reservedPool)
 	assert.ErrorIs(t, sharedPool.SetCpus(allCores), err)
 
 }
@@ -334,7 +394,7 @@ func TestReservedPoolType_SetCores(t *testing.T) {
 	host.On("GetSharedPool").Return(sharedPool)
 
 	requestedSetCores := CpuList{}
-	reservedPool := &reservedPoolType{poolImpl{host: host}}
+	reservedPool := &reservedPoolType{poolImpl{name: reservedPoolName, host: host}}
 	for i := 1; i <= 6; i++ {
 		core := new(cpuMock)
 		switch i {
@@ -367,8 +427,14 @@ func TestReservedPoolType_SetCores(t *testing.T) {
 	// now test case 2 where we expect error
 	allCores[0] = new(cpuMock)
 	allCores[0].(*cpuMock).On("getPool").Return(exclusivePool)
+	allCores[0].(*cpuMock).On("GetID").Return(uint(0))
+	exclusivePool.On("Name").Return("excl")
 
-	assert.ErrorContains(t, reservedPool.SetCpus(CpuList{allCores[0]}), "exclusive to reserved")
+	err := reservedPool.SetCpus(CpuList{allCores[0]})
+	assert.ErrorContains(t, err, "exclusive to reserved")
+	var transitionErr *PoolTransitionError
+	assert.ErrorAs(t, err, &transitionErr)
+	assert.Equal(t, PoolTransitionError{CpuID: 0, From: "excl", To: reservedPoolName, Reason: "cpus cannot be moved directly from exclusive to reserved pool"}, *transitionErr)
 }
 
 func TestExclusivePoolType_SetCores(t *testing.T) {
@@ -390,6 +456,7 @@ func TestExclusivePoolType_SetCores(t *testing.T) {
 			core.On("getPool").Return(sharedPool)
 		case 2:
 			core.On("SetPool", exclusivePool).Return(nil)
+			core.On("getPool").Return(sharedPool)
 		}
 
 		allCores[i] = core
@@ -406,6 +473,7 @@ func TestExclusivePoolType_SetCores(t *testing.T) {
 	err := fmt.Errorf("borked")
 	allCores[0] = new(cpuMock)
 	allCores[0].(*cpuMock).On("SetPool", mock.Anything).Return(err)
+	allCores[0].(*cpuMock).On("getPool").Return(sharedPool)
 	assert.ErrorIs(t, exclusivePool.SetCpus(CpuList{allCores[0]}), err)
 }
 
@@ -445,11 +513,15 @@ func TestExclusivePoolType_Remove(t *testing.T) {
 	host.On("GetAllCpus").Return(new(CpuList))
 
 	pool := &exclusivePoolType{poolImpl{host: host}}
-	pools := PoolList{pool}
-	host.On("GetAllExclusivePools").Return(&pools)
+	host.On("removeExclusivePool", pool).Return(nil)
 	assert.NoError(t, pool.Remove())
 	host.AssertExpectations(t)
-	assert.NotContains(t, pools, pool)
+
+	host = new(hostMock)
+	host.On("GetAllCpus").Return(new(CpuList))
+	pool = &exclusivePoolType{poolImpl{host: host}}
+	host.On("removeExclusivePool", pool).Return(fmt.Errorf("not found"))
+	assert.Error(t, pool.Remove())
 }
 
 func TestPoolList_ByName(t *testing.T) {
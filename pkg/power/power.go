@@ -14,47 +14,64 @@ import (
 
 var basePath = "/sys/devices/system/cpu"
 
-type featureID uint
+// FeatureID identifies a functionality of the library, String returns its stable name
+type FeatureID uint
 
 const (
 	sharedPoolName                    = "sharedPool"
 	reservedPoolName                  = "reservedPool"
-	FrequencyScalingFeature featureID = iota
+	FrequencyScalingFeature FeatureID = iota
 	EPPFeature
 	CStatesFeature
 	UncoreFeature
+	PMQoSFeature
+	IdleGovernorFeature
+	EPBFeature
 )
 
 type LibConfig struct {
 	CpuPath    string
 	ModulePath string
 	Cores      uint
+	// policy for combining uncore frequencies requested by pools sharing a die
+	UncorePoolPolicy UncorePoolPolicy
+	// read sysfs files before skipping writes of values the library applied already, detects changes made
+	// outside the library at the cost of a read per file
+	VerifySysfsWrites bool
+	// number of cpus consolidated concurrently when a pool setting changes, 0 or 1 consolidates sequentially
+	ConsolidationWorkers uint
 }
 
 // initialized with null logger, can be set to proper logger with SetLogger
 var log = logr.Discard()
 
+// features defined by the library with their stable names, used in logs and serialized feature info
+var featureDefinitions = []featureDefinition{
+	{id: FrequencyScalingFeature, name: "frequency-scaling", initFunc: initScalingDriver},
+	{id: EPPFeature, name: "epp", initFunc: initEpp},
+	{id: CStatesFeature, name: "c-states", initFunc: initCStates},
+	{id: UncoreFeature, name: "uncore", initFunc: initUncore},
+	{id: PMQoSFeature, name: "pm-qos", initFunc: initPMQoS},
+	{id: IdleGovernorFeature, name: "idle-governor", initFunc: initIdleGovernor},
+	{id: EPBFeature, name: "epb", initFunc: initEpb},
+}
+
+type featureDefinition struct {
+	id       FeatureID
+	name     string
+	initFunc func() featureStatus
+}
+
 // default declaration of defined features, defined to uninitialized state
-var featureList FeatureSet = map[featureID]*featureStatus{
-	EPPFeature: {
-		err:      uninitialisedErr,
-		initFunc: initEpp,
-	},
-	FrequencyScalingFeature: {
-		err:      uninitialisedErr,
-		initFunc: initScalingDriver,
-	},
-	CStatesFeature: {
-		err:      uninitialisedErr,
-		initFunc: initCStates,
-	},
-	UncoreFeature: {
-		err:      uninitialisedErr,
-		initFunc: initUncore,
-	},
-}
-var uninitialisedErr = fmt.Errorf("feature uninitialized")
-var undefinederr = fmt.Errorf("feature undefined")
+var featureList = newFeatureSet()
+
+func newFeatureSet() FeatureSet {
+	set := make(FeatureSet, len(featureDefinitions))
+	for _, definition := range featureDefinitions {
+		set[definition.id] = &featureStatus{err: uninitialisedErr, initFunc: definition.initFunc}
+	}
+	return set
+}
 
 // featureStatus stores feature name, driver and if feature is not supported, error describing the reason
 type featureStatus struct {
@@ -62,6 +79,8 @@ type featureStatus struct {
 	driver   string
 	err      error
 	initFunc func() featureStatus
+	// sysfs paths and capabilities of the feature, reported by FeatureInfo
+	details func() featureDetails
 }
 
 func (f *featureStatus) Name() string {
@@ -79,7 +98,7 @@ func (f *featureStatus) isSupported() bool {
 
 // FeatureSet stores info of about functionalities supported by the power library
 // on current system
-type FeatureSet map[featureID]*featureStatus
+type FeatureSet map[FeatureID]*featureStatus
 
 // initialise all defined features, return multiple errors for each failed feature
 func (set *FeatureSet) init() error {
@@ -106,7 +125,7 @@ func (set *FeatureSet) anySupported() bool {
 }
 
 // isFeatureIdSupported takes feature if, check if feature is supported on current system
-func (set *FeatureSet) isFeatureIdSupported(id featureID) bool {
+func (set *FeatureSet) isFeatureIdSupported(id FeatureID) bool {
 	feature, exists := (*set)[id]
 	if !exists {
 		return false
@@ -115,12 +134,15 @@ func (set *FeatureSet) isFeatureIdSupported(id featureID) bool {
 }
 
 // getFeatureIdError retrieve any error associated with a feature
-func (set *FeatureSet) getFeatureIdError(id featureID) error {
+func (set *FeatureSet) getFeatureIdError(id FeatureID) error {
 	feature, exists := (*set)[id]
 	if !exists {
-		return undefinederr
+		return &FeatureError{Feature: id, Err: undefinederr}
+	}
+	if feature.err == nil {
+		return nil
 	}
-	return feature.err
+	return &FeatureError{Feature: id, Name: feature.name, Err: feature.err}
 }
 
 // CreateInstance initialises the power library
@@ -129,6 +151,8 @@ func (set *FeatureSet) getFeatureIdError(id featureID) error {
 // if fatal errors occurred returns nil and error
 // if non-fatal error occurred Host object and error are returned
 func CreateInstance(hostName string) (Host, error) {
+	sysfsWrites.reset(verifySysfsWrites)
+	sharedTunables.reset()
 	allErrors := featureList.init()
 	if !featureList.anySupported() {
 		return nil, allErrors
@@ -147,6 +171,9 @@ func CreateInstanceWithConf(hostname string, conf LibConfig) (Host, error) {
 		kernelModulesFilePath = conf.ModulePath
 	}
 	getNumberOfCpus = func() uint { return conf.Cores }
+	uncorePoolPolicy = conf.UncorePoolPolicy
+	verifySysfsWrites = conf.VerifySysfsWrites
+	consolidationWorkers = conf.ConsolidationWorkers
 	return CreateInstance(hostname)
 }
 
@@ -207,7 +234,7 @@ func readStringFromFile(filePath string) (string, error) {
 
 // IsFeatureSupported checks if any number of features is supported. if any of the checked features is not supported
 // return false
-func IsFeatureSupported(features ...featureID) bool {
+func IsFeatureSupported(features ...FeatureID) bool {
 	for _, feature := range features {
 		if !featureList.isFeatureIdSupported(feature) {
 			return false
@@ -12,18 +12,36 @@ type profileImpl struct {
 	efficientMin uint
 	epp          string
 	governor     string
+	// numeric energy performance bias, empty if not set
+	epb string
+	// frequencies resolved for each cpu, take precedence over absolute frequencies if set
+	minLevel FreqLevel
+	maxLevel FreqLevel
+	// governor specific tunables, file name to value
+	governorTunables map[string]uint
+	// fixed frequencies for userspace governor, 0 if not set
+	setSpeed          uint
+	efficientSetSpeed uint
 	// todo classification
 }
 
+// ProfileOption sets optional properties of a power profile, passed to profile constructors
+type ProfileOption func(profile *profileImpl) error
+
 // Profile contains scaling driver information
 type Profile interface {
 	Name() string
 	Epp() string
+	Epb() string
 	MaxFreq() uint
 	EfficientMaxFreq() uint
 	MinFreq() uint
 	EfficientMinFreq() uint
 	Governor() string
+	GovernorTunables() map[string]uint
+	SetSpeed() uint
+	FreqLevels() (FreqLevel, FreqLevel)
+	EfficientSetSpeed() uint
 }
 
 var availableGovs []string
@@ -31,7 +49,7 @@ var availableGovs []string
 // todo add simple constructor that determines frequencies automagically?
 
 // NewPowerProfile creates a power profile,
-func NewPowerProfile(name string, minFreq uint, maxFreq uint, governor string, epp string) (Profile, error) {
+func NewPowerProfile(name string, minFreq uint, maxFreq uint, governor string, epp string, options ...ProfileOption) (Profile, error) {
 	if !featureList.isFeatureIdSupported(FrequencyScalingFeature) {
 		return nil, featureList.getFeatureIdError(FrequencyScalingFeature)
 	}
@@ -45,27 +63,38 @@ func NewPowerProfile(name string, minFreq uint, maxFreq uint, governor string, e
 		governor = defaultGovernor
 	}
 	if !checkGov(governor) { //todo determine by reading available governors, its different for acpi Driver
-		return nil, fmt.Errorf("governor can only be set to the following %v", availableGovs)
+		return nil, &NotAvailableError{Setting: "governor", Value: governor, Available: availableGovs}
 
 	}
-	if epp != "" && governor == cpuPolicyPerformance && epp != cpuPolicyPerformance {
-		return nil, fmt.Errorf("only '%s' epp can be used with '%s' governor", cpuPolicyPerformance, cpuPolicyPerformance)
+	if err := validateEpp(epp, governor); err != nil {
+		return nil, err
+	}
+
+	available, err := readAvailableFrequencies(0)
+	if err != nil {
+		return nil, err
 	}
+	min := normalizeProfileFreq(name, "min", minFreq*1000, available)
+	max := normalizeProfileFreq(name, "max", maxFreq*1000, available)
 
 	log.Info("creating powerProfile object", "name", name)
-	return &profileImpl{
+	profile := &profileImpl{
 		name:         name,
-		max:          maxFreq * 1000,
-		min:          minFreq * 1000,
-		efficientMax: maxFreq * 1000,
-		efficientMin: minFreq * 1000,
+		max:          max,
+		min:          min,
+		efficientMax: max,
+		efficientMin: min,
 		epp:          epp,
 		governor:     governor,
-	}, nil
+	}
+	if err := profile.applyOptions(options); err != nil {
+		return nil, err
+	}
+	return profile, nil
 }
 
 // creates a Power Profile for efficient and performant cores
-func NewEcorePowerProfile(name string, minFreq uint, maxFreq uint, emin uint, emax uint, governor string, epp string) (Profile, error) {
+func NewEcorePowerProfile(name string, minFreq uint, maxFreq uint, emin uint, emax uint, governor string, epp string, options ...ProfileOption) (Profile, error) {
 	if !featureList.isFeatureIdSupported(FrequencyScalingFeature) {
 		return nil, featureList.getFeatureIdError(FrequencyScalingFeature)
 	}
@@ -79,29 +108,113 @@ func NewEcorePowerProfile(name string, minFreq uint, maxFreq uint, emin uint, em
 		governor = defaultGovernor
 	}
 	if !checkGov(governor) { //todo determine by reading available governors, its different for acpi Driver
-		return nil, fmt.Errorf("governor can only be set to the following %v", availableGovs)
+		return nil, &NotAvailableError{Setting: "governor", Value: governor, Available: availableGovs}
 
 	}
-	if epp != "" && governor == cpuPolicyPerformance && epp != cpuPolicyPerformance {
-		return nil, fmt.Errorf("only '%s' epp can be used with '%s' governor", cpuPolicyPerformance, cpuPolicyPerformance)
+	if err := validateEpp(epp, governor); err != nil {
+		return nil, err
+	}
+
+	available, err := readAvailableFrequencies(0)
+	if err != nil {
+		return nil, err
 	}
 
 	log.Info("creating powerProfile object", "name", name)
-	return &profileImpl{
+	profile := &profileImpl{
 		name:         name,
-		max:          maxFreq * 1000,
-		min:          minFreq * 1000,
-		efficientMax: emax * 1000,
-		efficientMin: emin * 1000,
+		max:          normalizeProfileFreq(name, "max", maxFreq*1000, available),
+		min:          normalizeProfileFreq(name, "min", minFreq*1000, available),
+		efficientMax: normalizeProfileFreq(name, "efficientMax", emax*1000, available),
+		efficientMin: normalizeProfileFreq(name, "efficientMin", emin*1000, available),
 		epp:          epp,
 		governor:     governor,
-	}, nil
+	}
+	if err := profile.applyOptions(options); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// WithSetSpeed pins frequencies of performant and efficient cores in MHz, requires the userspace governor.
+// frequencies are normalized like profile frequencies and have to be within the profile frequency range,
+// for profiles using frequency levels the range is checked for each cpu when the profile is applied
+func WithSetSpeed(freq uint, efficientFreq uint) ProfileOption {
+	return func(profile *profileImpl) error {
+		if profile.governor != cpuPolicyUserspace {
+			return fmt.Errorf("set speed requires '%s' governor", cpuPolicyUserspace)
+		}
+		available, err := readAvailableFrequencies(0)
+		if err != nil {
+			return err
+		}
+		profile.setSpeed = normalizeProfileFreq(profile.name, "setSpeed", freq*1000, available)
+		profile.efficientSetSpeed = normalizeProfileFreq(profile.name, "efficientSetSpeed", efficientFreq*1000, available)
+		return nil
+	}
+}
+
+func (p *profileImpl) applyOptions(options []ProfileOption) error {
+	for _, option := range options {
+		if err := option(p); err != nil {
+			return fmt.Errorf("invalid power profile %s: %w", p.name, err)
+		}
+	}
+	if err := p.validateOptions(); err != nil {
+		return fmt.Errorf("invalid power profile %s: %w", p.name, err)
+	}
+	return nil
+}
+
+// checks options depending on each other or on the profile frequencies, regardless of the order they were passed in
+func (p *profileImpl) validateOptions() error {
+	if err := validateFreqLevelOrder(p.minLevel, p.maxLevel); err != nil {
+		return err
+	}
+	if p.setSpeed == 0 && p.efficientSetSpeed == 0 {
+		return nil
+	}
+	// levels are resolved for each cpu, set speed is checked against them when the profile is applied
+	if p.minLevel != "" || p.maxLevel != "" {
+		return nil
+	}
+	if p.setSpeed < p.min || p.setSpeed > p.max {
+		return &OutOfRangeError{Setting: "set speed", Value: p.setSpeed, Min: p.min, Max: p.max}
+	}
+	if p.efficientSetSpeed < p.efficientMin || p.efficientSetSpeed > p.efficientMax {
+		return &OutOfRangeError{Setting: "efficient set speed", Value: p.efficientSetSpeed, Min: p.efficientMin, Max: p.efficientMax}
+	}
+	return nil
+}
+
+// WithGovernorTunables sets tunables of the profile governor, eg. rate_limit_us of schedutil or up_threshold of
+// ondemand. tunables are validated against files exposed by the governor if it is already in use,
+// otherwise against tunables known for the governor
+func WithGovernorTunables(tunables map[string]uint) ProfileOption {
+	return func(profile *profileImpl) error {
+		available, err := availableGovernorTunables(profile.governor)
+		if err != nil {
+			return err
+		}
+		profile.governorTunables = make(map[string]uint, len(tunables))
+		for name, value := range tunables {
+			if !containsString(available, name) {
+				return fmt.Errorf("tunable %s is not supported by %s governor, supported tunables: %v", name, profile.governor, available)
+			}
+			profile.governorTunables[name] = value
+		}
+		return nil
+	}
 }
 
 func (p *profileImpl) Epp() string {
 	return p.epp
 }
 
+func (p *profileImpl) Epb() string {
+	return p.epb
+}
+
 func (p *profileImpl) MaxFreq() uint {
 	return p.max
 }
@@ -126,9 +239,34 @@ func (p *profileImpl) Governor() string {
 	return p.governor
 }
 
+// FreqLevels returns min and max frequency levels, empty if absolute frequencies are used
+func (p *profileImpl) FreqLevels() (FreqLevel, FreqLevel) {
+	return p.minLevel, p.maxLevel
+}
+
+func (p *profileImpl) SetSpeed() uint {
+	return p.setSpeed
+}
+
+func (p *profileImpl) EfficientSetSpeed() uint {
+	return p.efficientSetSpeed
+}
+
+func (p *profileImpl) GovernorTunables() map[string]uint {
+	tunables := make(map[string]uint, len(p.governorTunables))
+	for name, value := range p.governorTunables {
+		tunables[name] = value
+	}
+	return tunables
+}
+
 func checkGov(governor string) bool {
-	for _, element := range availableGovs {
-		if element == governor {
+	return containsString(availableGovs, governor)
+}
+
+func containsString(list []string, value string) bool {
+	for _, element := range list {
+		if element == value {
 			return true
 		}
 	}
@@ -2,6 +2,8 @@ package power
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -42,7 +44,8 @@ func TestNewProfile(t *testing.T) {
 	assert.Nil(t, profile)
 
 	profile, err = NewPowerProfile("name", 0, 100, "something random", "epp")
-	assert.ErrorContains(t, err, "governor can only be set to the following")
+	assert.ErrorIs(t, err, ErrNotAvailable)
+	assert.ErrorContains(t, err, "governor something random is not available")
 	assert.Nil(t, profile)
 }
 
@@ -72,3 +75,142 @@ func TestEfficientProfile(t *testing.T) {
 	assert.Nil(t, profile)
 
 }
+
+func TestWithGovernorTunables(t *testing.T) {
+	oldGovs := availableGovs
+	availableGovs = []string{cpuPolicyPowersave, cpuPolicySchedutil, cpuPolicyOndemand}
+	defer func() { availableGovs = oldGovs }()
+	defer setupCpuScalingTests(map[string]map[string]string{"cpu0": {}})()
+
+	// governor not in use, validated against known tunables
+	profile, err := NewPowerProfile("name", 0, 100, cpuPolicySchedutil, "", WithGovernorTunables(map[string]uint{"rate_limit_us": 500}))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]uint{"rate_limit_us": 500}, profile.GovernorTunables())
+
+	_, err = NewPowerProfile("name", 0, 100, cpuPolicySchedutil, "", WithGovernorTunables(map[string]uint{"up_threshold": 80}))
+	assert.ErrorContains(t, err, "tunable up_threshold is not supported by schedutil governor")
+
+	_, err = NewEcorePowerProfile("name", 0, 100, 0, 100, cpuPolicyPowersave, "", WithGovernorTunables(map[string]uint{"up_threshold": 80}))
+	assert.ErrorContains(t, err, "powersave governor has no tunables")
+
+	// governor in use, validated against exposed files
+	ondemandDir := filepath.Join(basePath, governorTunablesDir, cpuPolicyOndemand)
+	assert.NoError(t, os.MkdirAll(ondemandDir, os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(ondemandDir, "up_threshold"), []byte("95"), 0644))
+	profile, err = NewEcorePowerProfile("name", 0, 100, 0, 100, cpuPolicyOndemand, "", WithGovernorTunables(map[string]uint{"up_threshold": 80}))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]uint{"up_threshold": 80}, profile.GovernorTunables())
+	_, err = NewPowerProfile("name", 0, 100, cpuPolicyOndemand, "", WithGovernorTunables(map[string]uint{"sampling_rate": 10000}))
+	assert.ErrorContains(t, err, "not supported")
+
+	// returned tunables are a copy
+	profile.GovernorTunables()["up_threshold"] = 1
+	assert.Equal(t, uint(80), profile.GovernorTunables()["up_threshold"])
+}
+
+func TestWithSetSpeed(t *testing.T) {
+	oldGovs := availableGovs
+	availableGovs = []string{cpuPolicyPowersave, cpuPolicyUserspace}
+	defer func() { availableGovs = oldGovs }()
+	defer setupCpuScalingTests(map[string]map[string]string{"cpu0": {}})()
+
+	profile, err := NewEcorePowerProfile("name", 1000, 3000, 800, 2000, cpuPolicyUserspace, "", WithSetSpeed(2500, 1500))
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2_500_000), profile.SetSpeed())
+	assert.Equal(t, uint(1_500_000), profile.EfficientSetSpeed())
+
+	_, err = NewPowerProfile("name", 1000, 3000, cpuPolicyPowersave, "", WithSetSpeed(2000, 2000))
+	assert.ErrorContains(t, err, "requires 'userspace' governor")
+
+	_, err = NewEcorePowerProfile("name", 1000, 3000, 800, 2000, cpuPolicyUserspace, "", WithSetSpeed(3500, 1500))
+	assert.ErrorContains(t, err, "set speed 3500000 is out of range 1000000-3000000")
+	assert.ErrorIs(t, err, ErrOutOfRange)
+	_, err = NewEcorePowerProfile("name", 1000, 3000, 800, 2000, cpuPolicyUserspace, "", WithSetSpeed(2500, 2500))
+	assert.ErrorContains(t, err, "efficient set speed 2500000 is out of range 800000-2000000")
+
+	// acpi-cpufreq lists available frequencies
+	assert.NoError(t, os.WriteFile(filepath.Join(basePath, "cpu0", availFreqsFile), []byte("3000000 2000000 1000000 \n"), 0644))
+	profile, err = NewPowerProfile("name", 1000, 3000, cpuPolicyUserspace, "", WithSetSpeed(2000, 2000))
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2_000_000), profile.SetSpeed())
+	// normalized to the closest available frequency
+	profile, err = NewPowerProfile("name", 1000, 3000, cpuPolicyUserspace, "", WithSetSpeed(2600, 2400))
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3_000_000), profile.SetSpeed())
+	assert.Equal(t, uint(2_000_000), profile.EfficientSetSpeed())
+}
+
+func TestWithSetSpeedFreqLevels(t *testing.T) {
+	oldGovs := availableGovs
+	availableGovs = []string{cpuPolicyPowersave, cpuPolicyUserspace}
+	defer func() { availableGovs = oldGovs }()
+	defer setupCpuScalingTests(map[string]map[string]string{"cpu0": {}})()
+
+	// relative profiles have no absolute range, set speed is checked when applied
+	profile, err := NewRelativePowerProfile("pinned", FreqLevelMin, FreqLevelBase, cpuPolicyUserspace, "", WithSetSpeed(2000, 1500))
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2_000_000), profile.SetSpeed())
+	assert.Equal(t, uint(1_500_000), profile.EfficientSetSpeed())
+
+	// level option passed after set speed
+	profile, err = NewPowerProfile("pinned", 1000, 2000, cpuPolicyUserspace, "", WithSetSpeed(2500, 2500), WithMaxFreqLevel(FreqLevelMaxTurbo))
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2_500_000), profile.SetSpeed())
+
+	profile, err = ProfileSpec{
+		Name:     "pinned",
+		Governor: cpuPolicyUserspace,
+		MinLevel: FreqLevelMin,
+		MaxLevel: FreqLevelMaxTurbo,
+		SetSpeed: 2000,
+	}.NewProfile()
+	assert.NoError(t, err)
+	assert.Equal(t, uint(2_000_000), profile.SetSpeed())
+}
+
+func TestNormalizeCpuFreq(t *testing.T) {
+	origDriver := featureList[FrequencyScalingFeature].driver
+	defer func() { featureList[FrequencyScalingFeature].driver = origDriver }()
+
+	available := []uint{3_000_000, 2_000_000, 1_000_000}
+	assert.Equal(t, uint(2_000_000), normalizeCpuFreq(2_400_000, available))
+	assert.Equal(t, uint(3_000_000), normalizeCpuFreq(2_600_000, available))
+	// ties are resolved towards lower frequency
+	assert.Equal(t, uint(2_000_000), normalizeCpuFreq(2_500_000, available))
+	assert.Equal(t, uint(1_000_000), normalizeCpuFreq(100, available))
+
+	featureList[FrequencyScalingFeature].driver = "intel_pstate"
+	assert.Equal(t, uint(2_300_000), normalizeCpuFreq(2_345_678, nil))
+	featureList[FrequencyScalingFeature].driver = "acpi-cpufreq"
+	assert.Equal(t, uint(2_345_678), normalizeCpuFreq(2_345_678, nil))
+}
+
+func TestNewProfileNormalization(t *testing.T) {
+	oldGovs := availableGovs
+	availableGovs = []string{cpuPolicyPowersave}
+	defer func() { availableGovs = oldGovs }()
+	defer setupCpuScalingTests(map[string]map[string]string{"cpu0": {}})()
+	origDriver := featureList[FrequencyScalingFeature].driver
+	defer func() { featureList[FrequencyScalingFeature].driver = origDriver }()
+
+	// intel_pstate bus ratio
+	featureList[FrequencyScalingFeature].driver = "intel_pstate"
+	profile, err := NewEcorePowerProfile("name", 1234, 3456, 801, 2099, cpuPolicyPowersave, "")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1_200_000), profile.MinFreq())
+	assert.Equal(t, uint(3_400_000), profile.MaxFreq())
+	assert.Equal(t, uint(800_000), profile.EfficientMinFreq())
+	assert.Equal(t, uint(2_000_000), profile.EfficientMaxFreq())
+
+	// acpi-cpufreq discrete frequencies
+	featureList[FrequencyScalingFeature].driver = "acpi-cpufreq"
+	assert.NoError(t, os.WriteFile(filepath.Join(basePath, "cpu0", availFreqsFile), []byte("2101000 2100000 1800000 1500000\n"), 0644))
+	profile, err = NewPowerProfile("name", 1600, 2050, cpuPolicyPowersave, "")
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1_500_000), profile.MinFreq())
+	assert.Equal(t, uint(2_100_000), profile.MaxFreq())
+
+	assert.NoError(t, os.WriteFile(filepath.Join(basePath, "cpu0", availFreqsFile), []byte("nonsense"), 0644))
+	_, err = NewPowerProfile("name", 1600, 2050, cpuPolicyPowersave, "")
+	assert.ErrorContains(t, err, "failed to parse available frequency")
+}
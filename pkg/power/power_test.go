@@ -79,7 +79,7 @@ func TestFeatureSet_isFeatureIdSupported(t *testing.T) {
 func TestFeatureSet_getFeatureIdError(t *testing.T) {
 	// non existing
 	set := FeatureSet{}
-	assert.ErrorIs(t, undefinederr, set.getFeatureIdError(0))
+	assert.ErrorIs(t, set.getFeatureIdError(0), undefinederr)
 
 	// error
 	set[0] = &featureStatus{err: fmt.Errorf("")}
@@ -0,0 +1,78 @@
+package power
+
+import (
+	"fmt"
+)
+
+// ProfilePreset names one of the standard power profiles built from the frequencies, governors and EPP values
+// discovered on the host
+type ProfilePreset string
+
+const (
+	// highest frequency of every core type, performance governor and epp
+	PresetPerformance ProfilePreset = "performance"
+	// upper half of the cpuinfo range of every core type with a dynamic governor, balance_performance epp
+	PresetBalancePerformance ProfilePreset = "balance-performance"
+	// lower half of the cpuinfo range of every core type with a dynamic governor, balance_power epp
+	PresetBalancePower ProfilePreset = "balance-power"
+)
+
+// governors scaling frequency with load, in order of preference. powersave is only dynamic with an epp capable
+// driver, where it hands control to hardware, otherwise it keeps the cpu at its min frequency
+var dynamicGovernors = []string{cpuPolicySchedutil, cpuPolicyOndemand, cpuPolicyConservative}
+
+// PresetProfiles returns all supported presets
+func PresetProfiles() []ProfilePreset {
+	return []ProfilePreset{PresetPerformance, PresetBalancePerformance, PresetBalancePower}
+}
+
+// PresetProfile creates the power profile of a preset. frequencies are relative levels so the profile adapts to
+// each core type it's applied to, epp is only set if the driver supports it
+func (host *hostImpl) PresetProfile(preset ProfilePreset) (Profile, error) {
+	if !IsFeatureSupported(FrequencyScalingFeature) {
+		return nil, featureList.getFeatureIdError(FrequencyScalingFeature)
+	}
+	switch preset {
+	case PresetPerformance:
+		if !checkGov(cpuPolicyPerformance) {
+			return nil, fmt.Errorf("preset %s requires %s governor, available governors: %v", preset, cpuPolicyPerformance, availableGovs)
+		}
+		return NewRelativePowerProfile(string(preset), FreqLevelMaxTurbo, FreqLevelMaxTurbo, cpuPolicyPerformance, presetEpp(cpuPolicyPerformance))
+	case PresetBalancePerformance:
+		governor, err := presetDynamicGovernor(preset)
+		if err != nil {
+			return nil, err
+		}
+		return NewRelativePowerProfile(string(preset), "50%", FreqLevelMaxTurbo, governor, presetEpp("balance_performance"))
+	case PresetBalancePower:
+		governor, err := presetDynamicGovernor(preset)
+		if err != nil {
+			return nil, err
+		}
+		return NewRelativePowerProfile(string(preset), FreqLevelMin, "50%", governor, presetEpp("balance_power"))
+	}
+	return nil, fmt.Errorf("unknown profile preset %s, presets: %v", preset, PresetProfiles())
+}
+
+func presetDynamicGovernor(preset ProfilePreset) (string, error) {
+	if IsFeatureSupported(EPPFeature) && checkGov(cpuPolicyPowersave) {
+		return cpuPolicyPowersave, nil
+	}
+	for _, governor := range dynamicGovernors {
+		if checkGov(governor) {
+			return governor, nil
+		}
+	}
+	return "", fmt.Errorf("preset %s requires a dynamic governor, available governors: %v", preset, availableGovs)
+}
+
+// returns epp for a preset, empty if the driver doesn't expose it
+func presetEpp(epp string) string {
+	if !IsFeatureSupported(EPPFeature) {
+		return ""
+	}
+	if availableEpps != nil && !containsString(availableEpps, epp) {
+		return ""
+	}
+	return epp
+}
@@ -0,0 +1,132 @@
+package power
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// initialises scaling and epp features against a fake sysfs tree of a single cpu
+func setupPresetTests(cpu0 map[string]string, availEpps string) func() {
+	teardown := setupCpuScalingTests(map[string]map[string]string{"cpu0": cpu0})
+	origGovs := availableGovs
+	origEpps := availableEpps
+	origEppStatus := *featureList[EPPFeature]
+	origDriver := featureList[FrequencyScalingFeature].driver
+	if availEpps != "" {
+		os.WriteFile(filepath.Join(basePath, "cpu0", availEppFile), []byte(availEpps+"\n"), 0644)
+	}
+	scaling := initScalingDriver()
+	featureList[FrequencyScalingFeature].err = scaling.err
+	featureList[FrequencyScalingFeature].driver = scaling.driver
+	epp := initEpp()
+	featureList[EPPFeature].err = epp.err
+	coreTypes = CoreTypeList{&CpuFrequencySet{min: defaultPowerProfile.min, max: defaultPowerProfile.max}}
+	return func() {
+		availableGovs = origGovs
+		availableEpps = origEpps
+		*featureList[EPPFeature] = origEppStatus
+		featureList[FrequencyScalingFeature].driver = origDriver
+		teardown()
+	}
+}
+
+func TestHostImpl_PresetProfileIntelPstate(t *testing.T) {
+	defer setupPresetTests(map[string]string{
+		"driver":              "intel_pstate",
+		"max":                 "4800000",
+		"min":                 "800000",
+		"governor":            cpuPolicyPowersave,
+		"available_governors": "performance powersave",
+		"epp":                 "balance_performance",
+	}, "default performance balance_performance balance_power power")()
+	host := &hostImpl{}
+	cpu := &cpuImpl{id: 0, mutex: &sync.Mutex{}, core: &cpuCore{coreType: 0}}
+
+	profile, err := host.PresetProfile(PresetPerformance)
+	assert.NoError(t, err)
+	assert.Equal(t, cpuPolicyPerformance, profile.Governor())
+	assert.Equal(t, cpuPolicyPerformance, profile.Epp())
+	assert.NoError(t, cpu.setDriverValues(profile))
+	minFreq, _ := readCpuUintProperty(0, scalingMinFile)
+	maxFreq, _ := readCpuUintProperty(0, scalingMaxFile)
+	assert.Equal(t, uint(4_800_000), minFreq)
+	assert.Equal(t, uint(4_800_000), maxFreq)
+
+	profile, err = host.PresetProfile(PresetBalancePerformance)
+	assert.NoError(t, err)
+	assert.Equal(t, cpuPolicyPowersave, profile.Governor())
+	assert.Equal(t, "balance_performance", profile.Epp())
+	assert.NoError(t, cpu.setDriverValues(profile))
+	minFreq, _ = readCpuUintProperty(0, scalingMinFile)
+	maxFreq, _ = readCpuUintProperty(0, scalingMaxFile)
+	assert.Equal(t, uint(2_800_000), minFreq)
+	assert.Equal(t, uint(4_800_000), maxFreq)
+
+	profile, err = host.PresetProfile(PresetBalancePower)
+	assert.NoError(t, err)
+	assert.Equal(t, cpuPolicyPowersave, profile.Governor())
+	assert.Equal(t, "balance_power", profile.Epp())
+	assert.NoError(t, cpu.setDriverValues(profile))
+	minFreq, _ = readCpuUintProperty(0, scalingMinFile)
+	maxFreq, _ = readCpuUintProperty(0, scalingMaxFile)
+	assert.Equal(t, uint(800_000), minFreq)
+	assert.Equal(t, uint(2_800_000), maxFreq)
+
+	_, err = host.PresetProfile("turbo")
+	assert.ErrorContains(t, err, "unknown profile preset turbo")
+}
+
+func TestHostImpl_PresetProfileAcpiCpufreq(t *testing.T) {
+	defer setupPresetTests(map[string]string{
+		"driver":              "acpi-cpufreq",
+		"max":                 "3000000",
+		"min":                 "800000",
+		"governor":            cpuPolicySchedutil,
+		"available_governors": "conservative ondemand userspace powersave performance schedutil",
+	}, "")()
+	assert.NoError(t, os.WriteFile(filepath.Join(basePath, "cpu0", availFreqsFile), []byte("3000000 2400000 1800000 1200000 800000\n"), 0644))
+	host := &hostImpl{}
+	cpu := &cpuImpl{id: 0, mutex: &sync.Mutex{}, core: &cpuCore{coreType: 0}}
+
+	profile, err := host.PresetProfile(PresetPerformance)
+	assert.NoError(t, err)
+	assert.Equal(t, cpuPolicyPerformance, profile.Governor())
+	assert.Empty(t, profile.Epp())
+
+	profile, err = host.PresetProfile(PresetBalancePerformance)
+	assert.NoError(t, err)
+	assert.Equal(t, cpuPolicySchedutil, profile.Governor())
+	assert.Empty(t, profile.Epp())
+	assert.NoError(t, cpu.setDriverValues(profile))
+	// half of the range snaps to the closest frequency listed by the driver
+	minFreq, _ := readCpuUintProperty(0, scalingMinFile)
+	maxFreq, _ := readCpuUintProperty(0, scalingMaxFile)
+	assert.Equal(t, uint(1_800_000), minFreq)
+	assert.Equal(t, uint(3_000_000), maxFreq)
+
+	profile, err = host.PresetProfile(PresetBalancePower)
+	assert.NoError(t, err)
+	assert.NoError(t, cpu.setDriverValues(profile))
+	minFreq, _ = readCpuUintProperty(0, scalingMinFile)
+	maxFreq, _ = readCpuUintProperty(0, scalingMaxFile)
+	assert.Equal(t, uint(800_000), minFreq)
+	assert.Equal(t, uint(1_800_000), maxFreq)
+
+	// no dynamic governor
+	availableGovs = []string{cpuPolicyPerformance, cpuPolicyPowersave}
+	_, err = host.PresetProfile(PresetBalancePower)
+	assert.ErrorContains(t, err, "requires a dynamic governor")
+	availableGovs = []string{cpuPolicyPowersave}
+	_, err = host.PresetProfile(PresetPerformance)
+	assert.ErrorContains(t, err, "requires performance governor")
+}
+
+func TestHostImpl_PresetProfileUnsupported(t *testing.T) {
+	host := &hostImpl{}
+	_, err := host.PresetProfile(PresetPerformance)
+	assert.ErrorIs(t, err, uninitialisedErr)
+}
@@ -0,0 +1,173 @@
+package power
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProfileSpec is the serializable form of a power profile. frequencies are in MHz, same as constructor arguments
+type ProfileSpec struct {
+	Name              string          `json:"name" yaml:"name"`
+	Min               uint            `json:"min,omitempty" yaml:"min,omitempty"`
+	Max               uint            `json:"max,omitempty" yaml:"max,omitempty"`
+	EfficientMin      uint            `json:"efficientMin,omitempty" yaml:"efficientMin,omitempty"`
+	EfficientMax      uint            `json:"efficientMax,omitempty" yaml:"efficientMax,omitempty"`
+	MinLevel          FreqLevel       `json:"minLevel,omitempty" yaml:"minLevel,omitempty"`
+	MaxLevel          FreqLevel       `json:"maxLevel,omitempty" yaml:"maxLevel,omitempty"`
+	Governor          string          `json:"governor,omitempty" yaml:"governor,omitempty"`
+	GovernorTunables  map[string]uint `json:"governorTunables,omitempty" yaml:"governorTunables,omitempty"`
+	Epp               string          `json:"epp,omitempty" yaml:"epp,omitempty"`
+	Epb               string          `json:"epb,omitempty" yaml:"epb,omitempty"`
+	SetSpeed          uint            `json:"setSpeed,omitempty" yaml:"setSpeed,omitempty"`
+	EfficientSetSpeed uint            `json:"efficientSetSpeed,omitempty" yaml:"efficientSetSpeed,omitempty"`
+}
+
+// ProfileDiff is a single setting that differs between two profiles
+type ProfileDiff struct {
+	Field string
+	Old   string
+	New   string
+}
+
+func (d ProfileDiff) String() string {
+	return fmt.Sprintf("%s: %s -> %s", d.Field, d.Old, d.New)
+}
+
+// SpecFromProfile describes a profile, nil profile results in an empty spec
+func SpecFromProfile(profile Profile) ProfileSpec {
+	if profile == nil {
+		return ProfileSpec{}
+	}
+	minLevel, maxLevel := profile.FreqLevels()
+	spec := ProfileSpec{
+		Name:              profile.Name(),
+		Min:               profile.MinFreq() / 1000,
+		Max:               profile.MaxFreq() / 1000,
+		EfficientMin:      profile.EfficientMinFreq() / 1000,
+		EfficientMax:      profile.EfficientMaxFreq() / 1000,
+		MinLevel:          minLevel,
+		MaxLevel:          maxLevel,
+		Governor:          profile.Governor(),
+		Epp:               profile.Epp(),
+		Epb:               profile.Epb(),
+		SetSpeed:          profile.SetSpeed() / 1000,
+		EfficientSetSpeed: profile.EfficientSetSpeed() / 1000,
+	}
+	if tunables := profile.GovernorTunables(); len(tunables) > 0 {
+		spec.GovernorTunables = tunables
+	}
+	return spec
+}
+
+// NewProfile creates a profile from the spec using the validating constructors. a spec without absolute
+// frequencies but with both levels results in a relative profile
+func (spec ProfileSpec) NewProfile() (Profile, error) {
+	var options []ProfileOption
+	if len(spec.GovernorTunables) > 0 {
+		options = append(options, WithGovernorTunables(spec.GovernorTunables))
+	}
+	if spec.Epb != "" {
+		options = append(options, WithEpb(spec.Epb))
+	}
+	if spec.SetSpeed != 0 || spec.EfficientSetSpeed != 0 {
+		options = append(options, WithSetSpeed(spec.SetSpeed, spec.EfficientSetSpeed))
+	}
+	isRelative := spec.Min == 0 && spec.Max == 0 && spec.EfficientMin == 0 && spec.EfficientMax == 0
+	if isRelative && spec.MinLevel != "" && spec.MaxLevel != "" {
+		return NewRelativePowerProfile(spec.Name, spec.MinLevel, spec.MaxLevel, spec.Governor, spec.Epp, options...)
+	}
+	if spec.MinLevel != "" {
+		options = append(options, WithMinFreqLevel(spec.MinLevel))
+	}
+	if spec.MaxLevel != "" {
+		options = append(options, WithMaxFreqLevel(spec.MaxLevel))
+	}
+	hasEfficient := spec.EfficientMin != 0 || spec.EfficientMax != 0
+	if hasEfficient && (spec.EfficientMin != spec.Min || spec.EfficientMax != spec.Max) {
+		return NewEcorePowerProfile(spec.Name, spec.Min, spec.Max, spec.EfficientMin, spec.EfficientMax, spec.Governor, spec.Epp, options...)
+	}
+	return NewPowerProfile(spec.Name, spec.Min, spec.Max, spec.Governor, spec.Epp, options...)
+}
+
+func (p *profileImpl) MarshalJSON() ([]byte, error) {
+	return json.Marshal(SpecFromProfile(p))
+}
+
+func (p *profileImpl) MarshalYAML() (interface{}, error) {
+	return SpecFromProfile(p), nil
+}
+
+// UnmarshalProfile creates a profile from its JSON or YAML spec, validated the same way as profiles
+// created by constructors
+func UnmarshalProfile(data []byte) (Profile, error) {
+	var spec ProfileSpec
+	// YAML is a superset of JSON
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse power profile: %w", err)
+	}
+	return spec.NewProfile()
+}
+
+// ProfilesEqual checks whether two profiles result in the same cpu configuration. names are not compared
+func ProfilesEqual(a Profile, b Profile) bool {
+	return len(DiffProfiles(a, b)) == 0
+}
+
+// DiffProfiles lists settings that differ between from and to profiles, names are not compared.
+// a nil profile is treated as a profile with no settings
+func DiffProfiles(from Profile, to Profile) []ProfileDiff {
+	oldSpec, newSpec := SpecFromProfile(from), SpecFromProfile(to)
+	var diffs []ProfileDiff
+	compare := func(field string, oldValue, newValue any) {
+		oldStr, newStr := fmt.Sprint(oldValue), fmt.Sprint(newValue)
+		if oldStr != newStr {
+			diffs = append(diffs, ProfileDiff{Field: field, Old: oldStr, New: newStr})
+		}
+	}
+	compare("min", oldSpec.Min, newSpec.Min)
+	compare("max", oldSpec.Max, newSpec.Max)
+	compare("efficientMin", oldSpec.EfficientMin, newSpec.EfficientMin)
+	compare("efficientMax", oldSpec.EfficientMax, newSpec.EfficientMax)
+	compare("minLevel", oldSpec.MinLevel, newSpec.MinLevel)
+	compare("maxLevel", oldSpec.MaxLevel, newSpec.MaxLevel)
+	compare("governor", oldSpec.Governor, newSpec.Governor)
+	compare("epp", oldSpec.Epp, newSpec.Epp)
+	compare("epb", oldSpec.Epb, newSpec.Epb)
+	compare("setSpeed", oldSpec.SetSpeed, newSpec.SetSpeed)
+	compare("efficientSetSpeed", oldSpec.EfficientSetSpeed, newSpec.EfficientSetSpeed)
+
+	tunableNames := make(map[string]struct{})
+	for name := range oldSpec.GovernorTunables {
+		tunableNames[name] = struct{}{}
+	}
+	for name := range newSpec.GovernorTunables {
+		tunableNames[name] = struct{}{}
+	}
+	sortedNames := make([]string, 0, len(tunableNames))
+	for name := range tunableNames {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+	for _, name := range sortedNames {
+		oldValue, oldSet := oldSpec.GovernorTunables[name]
+		newValue, newSet := newSpec.GovernorTunables[name]
+		if oldSet != newSet || oldValue != newValue {
+			diffs = append(diffs, ProfileDiff{
+				Field: "governorTunables." + name,
+				Old:   tunableString(oldValue, oldSet),
+				New:   tunableString(newValue, newSet),
+			})
+		}
+	}
+	return diffs
+}
+
+func tunableString(value uint, set bool) string {
+	if !set {
+		return "<unset>"
+	}
+	return fmt.Sprint(value)
+}
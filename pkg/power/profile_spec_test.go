@@ -0,0 +1,101 @@
+package power
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func setupProfileSpecTests() func() {
+	oldGovs := availableGovs
+	availableGovs = []string{cpuPolicyPowersave, cpuPolicyPerformance, cpuPolicySchedutil}
+	teardown := setupCpuScalingTests(map[string]map[string]string{"cpu0": {"max": "3500000", "min": "800000"}})
+	return func() {
+		teardown()
+		availableGovs = oldGovs
+	}
+}
+
+func TestProfileSpec_RoundTrip(t *testing.T) {
+	defer setupProfileSpecTests()()
+
+	profile, err := NewPowerProfile("pwr", 1000, 3000, cpuPolicySchedutil, "", WithGovernorTunables(map[string]uint{"rate_limit_us": 500}))
+	assert.NoError(t, err)
+
+	data, err := json.Marshal(profile)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"pwr","min":1000,"max":3000,"efficientMin":1000,"efficientMax":3000,"governor":"schedutil","governorTunables":{"rate_limit_us":500}}`, string(data))
+	fromJson, err := UnmarshalProfile(data)
+	assert.NoError(t, err)
+	assert.Equal(t, profile, fromJson)
+
+	data, err = yaml.Marshal(profile)
+	assert.NoError(t, err)
+	fromYaml, err := UnmarshalProfile(data)
+	assert.NoError(t, err)
+	assert.Equal(t, profile, fromYaml)
+
+	ecore, err := NewEcorePowerProfile("ecore", 1000, 3000, 800, 2000, cpuPolicyPowersave, "")
+	assert.NoError(t, err)
+	data, err = json.Marshal(ecore)
+	assert.NoError(t, err)
+	fromJson, err = UnmarshalProfile(data)
+	assert.NoError(t, err)
+	assert.Equal(t, ecore, fromJson)
+
+	relative, err := NewRelativePowerProfile("relative", "50%", FreqLevelMaxTurbo, cpuPolicyPowersave, "")
+	assert.NoError(t, err)
+	data, err = yaml.Marshal(relative)
+	assert.NoError(t, err)
+	assert.Equal(t, "name: relative\nminLevel: 50%\nmaxLevel: max-turbo\ngovernor: powersave\n", string(data))
+	fromYaml, err = UnmarshalProfile(data)
+	assert.NoError(t, err)
+	assert.Equal(t, relative, fromYaml)
+}
+
+func TestUnmarshalProfile(t *testing.T) {
+	defer setupProfileSpecTests()()
+
+	profile, err := UnmarshalProfile([]byte("name: perf\nmin: 3000\nmax: 3500\ngovernor: performance\nepp: performance\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3_000_000), profile.MinFreq())
+	assert.Equal(t, cpuPolicyPerformance, profile.Governor())
+
+	// validated by constructors
+	_, err = UnmarshalProfile([]byte(`{"name": "bad", "min": 3000, "max": 1000}`))
+	assert.ErrorContains(t, err, "max Freq can't be lower than min")
+	_, err = UnmarshalProfile([]byte(`{"name": "bad", "min": 1000, "max": 3000, "governor": "ondemand"}`))
+	assert.ErrorIs(t, err, ErrNotAvailable)
+	_, err = UnmarshalProfile([]byte(`{"name": "bad", "min": 1000, "max": 3000, "maxLevel": "turbo"}`))
+	assert.ErrorContains(t, err, "unknown frequency level turbo")
+
+	_, err = UnmarshalProfile([]byte(`{"name": `))
+	assert.ErrorContains(t, err, "failed to parse power profile")
+}
+
+func TestDiffProfiles(t *testing.T) {
+	defer setupProfileSpecTests()()
+
+	current, err := NewPowerProfile("current", 1000, 3000, cpuPolicySchedutil, "", WithGovernorTunables(map[string]uint{"rate_limit_us": 500}))
+	assert.NoError(t, err)
+	same, err := NewPowerProfile("same", 1000, 3000, cpuPolicySchedutil, "", WithGovernorTunables(map[string]uint{"rate_limit_us": 500}))
+	assert.NoError(t, err)
+	assert.True(t, ProfilesEqual(current, same))
+	assert.Empty(t, DiffProfiles(current, same))
+
+	updated, err := NewPowerProfile("current", 1000, 3200, cpuPolicySchedutil, "")
+	assert.NoError(t, err)
+	assert.False(t, ProfilesEqual(current, updated))
+	assert.Equal(t, []ProfileDiff{
+		{Field: "max", Old: "3000", New: "3200"},
+		{Field: "efficientMax", Old: "3000", New: "3200"},
+		{Field: "governorTunables.rate_limit_us", Old: "500", New: "<unset>"},
+	}, DiffProfiles(current, updated))
+	assert.Equal(t, "max: 3000 -> 3200", DiffProfiles(current, updated)[0].String())
+
+	assert.True(t, ProfilesEqual(nil, nil))
+	assert.False(t, ProfilesEqual(nil, current))
+	assert.Contains(t, DiffProfiles(nil, current), ProfileDiff{Field: "governor", Old: "", New: cpuPolicySchedutil})
+}
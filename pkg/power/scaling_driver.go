@@ -7,7 +7,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 )
 
 const (
@@ -17,10 +20,14 @@ const (
 	cpuMinFreqFile = "cpufreq/cpuinfo_min_freq"
 	scalingMaxFile = "cpufreq/scaling_max_freq"
 	scalingMinFile = "cpufreq/scaling_min_freq"
+	setSpeedFile   = "cpufreq/scaling_setspeed"
+	// only listed by drivers with discrete frequencies, eg. acpi-cpufreq
+	availFreqsFile = "cpufreq/scaling_available_frequencies"
 
 	scalingGovFile = "cpufreq/scaling_governor"
 	availGovFile   = "cpufreq/scaling_available_governors"
 	eppFile        = "cpufreq/energy_performance_preference"
+	availEppFile   = "cpufreq/energy_performance_available_preferences"
 
 	defaultEpp      = "default"
 	defaultGovernor = cpuPolicyPowersave
@@ -31,8 +38,33 @@ const (
 	cpuPolicyOndemand     = "ondemand"
 	cpuPolicySchedutil    = "schedutil"
 	cpuPolicyConservative = "conservative"
+
+	// governor tunables are in cpuN/cpufreq/<governor> if the driver uses per policy governors,
+	// otherwise in cpufreq/<governor> shared by all cpus
+	governorTunablesDir = "cpufreq"
+
+	// intel_pstate operates on 100MHz bus ratios
+	pstateFreqGranularity uint = 100_000
 )
 
+// numeric values of named epp presets, as used by amd-pstate-epp
+var eppPresetValues = map[string]uint{
+	cpuPolicyPerformance:  0,
+	"balance_performance": 128,
+	"balance_power":       191,
+	"power":               255,
+}
+
+// populated during library initialisation if the driver lists available preferences
+var availableEpps []string
+
+// tunables of governors, used for validation when the governor is not in use and its directory does not exist
+var knownGovernorTunables = map[string][]string{
+	cpuPolicySchedutil:    {"rate_limit_us"},
+	cpuPolicyOndemand:     {"up_threshold", "sampling_rate", "sampling_down_factor", "ignore_nice_load", "powersave_bias", "io_is_busy"},
+	cpuPolicyConservative: {"up_threshold", "down_threshold", "sampling_rate", "sampling_down_factor", "freq_step", "ignore_nice_load"},
+}
+
 type (
 	CpuFrequencySet struct {
 		min uint
@@ -74,7 +106,7 @@ func (l *CoreTypeList) appendIfUnique(min uint, max uint) uint {
 var defaultPowerProfile *profileImpl
 
 func isScalingDriverSupported(driver string) bool {
-	for _, s := range []string{"intel_pstate", "intel_cpufreq", "acpi-cpufreq"} {
+	for _, s := range []string{"intel_pstate", "intel_cpufreq", "acpi-cpufreq", "amd-pstate-epp"} {
 		if driver == s {
 			return true
 		}
@@ -86,6 +118,7 @@ func initScalingDriver() featureStatus {
 	pStates := featureStatus{
 		name:     "Frequency-Scaling",
 		initFunc: initScalingDriver,
+		details:  scalingDriverDetails,
 	}
 	var err error
 	availableGovs, err = initAvailableGovernors()
@@ -114,10 +147,99 @@ func initEpp() featureStatus {
 	epp := featureStatus{
 		name:     "Energy-Performance-Preference",
 		initFunc: initEpp,
+		details:  eppDetails,
 	}
 	_, err := readCpuStringProperty(0, eppFile)
 	if os.IsNotExist(errors.Unwrap(err)) {
 		epp.err = fmt.Errorf("EPP file %s does not exist", eppFile)
+		return epp
+	}
+	// not exposed by all drivers, names are not validated if missing
+	if prefs, err := readCpuStringProperty(0, availEppFile); err == nil {
+		availableEpps = strings.Fields(prefs)
+	}
+	return epp
+}
+
+func scalingDriverDetails() featureDetails {
+	ranges := make([]map[string]uint, len(coreTypes))
+	for i, coreType := range coreTypes {
+		ranges[i] = map[string]uint{"min_khz": coreType.GetMin(), "max_khz": coreType.GetMax()}
+	}
+	return featureDetails{
+		paths: []string{
+			allCpusPath(pStatesDrvFile),
+			allCpusPath(scalingGovFile),
+			allCpusPath(availGovFile),
+			allCpusPath(cpuMinFreqFile),
+			allCpusPath(cpuMaxFreqFile),
+			allCpusPath(scalingMinFile),
+			allCpusPath(scalingMaxFile),
+			allCpusPath(setSpeedFile),
+		},
+		capabilities: map[string]any{
+			"governors":        availableGovs,
+			"frequency_ranges": ranges,
+		},
+	}
+}
+
+func eppDetails() featureDetails {
+	return featureDetails{
+		paths:        []string{allCpusPath(eppFile), allCpusPath(availEppFile)},
+		capabilities: map[string]any{"preferences": availableEpps},
+	}
+}
+
+// validateEpp checks that epp is one of the preferences exposed by the driver, or a numeric value 0-255 on drivers
+// accepting raw values. epp other than performance cannot be combined with performance governor
+func validateEpp(epp string, governor string) error {
+	if epp == "" {
+		return nil
+	}
+	value, isNumeric := parseNumericEpp(epp)
+	if governor == cpuPolicyPerformance && epp != cpuPolicyPerformance && !(isNumeric && value == 0) {
+		return fmt.Errorf("only '%s' epp can be used with '%s' governor", cpuPolicyPerformance, cpuPolicyPerformance)
+	}
+	if !IsFeatureSupported(EPPFeature) {
+		return nil
+	}
+	if isNumeric {
+		if value > 255 {
+			return &OutOfRangeError{Setting: "numeric epp", Value: value, Min: 0, Max: 255}
+		}
+		if !isNumericEppSupported() {
+			return fmt.Errorf("numeric epp values are %w with %s driver", ErrNotAvailable, featureList[FrequencyScalingFeature].driver)
+		}
+		return nil
+	}
+	if availableEpps != nil && !containsString(availableEpps, epp) {
+		return &NotAvailableError{Setting: "epp", Value: epp, Available: availableEpps}
+	}
+	return nil
+}
+
+func parseNumericEpp(epp string) (uint, bool) {
+	value, err := strconv.ParseUint(epp, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return uint(value), true
+}
+
+// only intel_pstate in active mode accepts raw epp values
+func isNumericEppSupported() bool {
+	return featureList[FrequencyScalingFeature].driver == "intel_pstate"
+}
+
+// eppValueToWrite maps named presets to numeric values on drivers accepting them. preset values of intel_pstate
+// differ between platforms, numeric values make profiles behave the same as on amd-pstate-epp
+func eppValueToWrite(epp string) string {
+	if !isNumericEppSupported() {
+		return epp
+	}
+	if value, exists := eppPresetValues[epp]; exists {
+		return fmt.Sprint(value)
 	}
 	return epp
 }
@@ -159,10 +281,42 @@ func generateDefaultProfile() error {
 	return nil
 }
 
+// SetPowerProfile sets a power profile on a single cpu, overriding the profile of its pool
+func (cpu *cpuImpl) SetPowerProfile(profile Profile) error {
+	if !IsFeatureSupported(FrequencyScalingFeature) {
+		return featureList.getFeatureIdError(FrequencyScalingFeature)
+	}
+	if profile == nil {
+		return fmt.Errorf("power profile cannot be nil, use ClearPowerProfile to remove it")
+	}
+	unlock := cpu.lockWithPools()
+	defer unlock()
+	cpu.powerProfile = profile
+	return cpu.updateFrequencies()
+}
+
+// ClearPowerProfile removes cpu specific power profile, profile of the pool will be applied instead
+func (cpu *cpuImpl) ClearPowerProfile() error {
+	if !IsFeatureSupported(FrequencyScalingFeature) {
+		return featureList.getFeatureIdError(FrequencyScalingFeature)
+	}
+	unlock := cpu.lockWithPools()
+	defer unlock()
+	cpu.powerProfile = nil
+	return cpu.updateFrequencies()
+}
+
+func (cpu *cpuImpl) getPowerProfile() Profile {
+	return cpu.powerProfile
+}
+
 func (cpu *cpuImpl) updateFrequencies() error {
 	if !IsFeatureSupported(FrequencyScalingFeature) {
 		return nil
 	}
+	if cpu.powerProfile != nil {
+		return cpu.setDriverValues(cpu.powerProfile)
+	}
 	if cpu.pool.GetPowerProfile() != nil {
 		return cpu.setDriverValues(cpu.pool.GetPowerProfile())
 	}
@@ -174,16 +328,41 @@ func (cpu *cpuImpl) setDriverValues(powerProfile Profile) error {
 	if err := cpu.writeGovernorValue(powerProfile.Governor()); err != nil {
 		return fmt.Errorf("failed to set governor for cpu %d: %w", cpu.id, err)
 	}
+	if err := cpu.writeGovernorTunables(powerProfile); err != nil {
+		return fmt.Errorf("failed to set governor tunables for cpu %d: %w", cpu.id, err)
+	}
 	if powerProfile.Epp() != "" {
 		if err := cpu.writeEppValue(powerProfile.Epp()); err != nil {
 			return fmt.Errorf("failed to set EPP value for cpu %d: %w", cpu.id, err)
 		}
 	}
-	minFreq, maxFreq := cpu.getFreqsToScale(powerProfile)
+	epb := powerProfile.Epb()
+	if epb == "" {
+		epb = cpu.initEpb
+	}
+	if epb != "" && IsFeatureSupported(EPBFeature) {
+		if err := cpu.writeEpbValue(epb); err != nil {
+			return fmt.Errorf("failed to set EPB value for cpu %d: %w", cpu.id, err)
+		}
+	}
+	minFreq, maxFreq, err := cpu.getFreqsToScale(powerProfile)
+	if err != nil {
+		return fmt.Errorf("failed to resolve frequencies for cpu %d: %w", cpu.id, err)
+	}
 	absMin, absMax := cpu.GetAbsMinMax()
-	if maxFreq > absMax || minFreq < absMin {
-		return fmt.Errorf("setting frequency %d-%d aborted as frequency range is min: %d max: %d. resetting to default",
-			powerProfile.MinFreq(), powerProfile.MaxFreq(), absMin, absMax)
+	if maxFreq > absMax || maxFreq < absMin {
+		return &OutOfRangeError{Setting: "max frequency", Value: maxFreq, Min: absMin, Max: absMax}
+	}
+	if minFreq < absMin || minFreq > absMax {
+		return &OutOfRangeError{Setting: "min frequency", Value: minFreq, Min: absMin, Max: absMax}
+	}
+	if minFreq > maxFreq {
+		return fmt.Errorf("setting frequency %d-%d aborted as min frequency is higher than max", minFreq, maxFreq)
+	}
+	// profiles using frequency levels are checked only here, once their range is known for the cpu
+	setSpeed := cpu.getSetSpeedToScale(powerProfile)
+	if setSpeed != 0 && (setSpeed < minFreq || setSpeed > maxFreq) {
+		return &OutOfRangeError{Setting: "set speed", Value: setSpeed, Min: minFreq, Max: maxFreq}
 	}
 	if err := cpu.writeScalingMaxFreq(maxFreq); err != nil {
 		return fmt.Errorf("failed to set MaxFreq value for cpu %d: %w", cpu.id, err)
@@ -191,61 +370,323 @@ func (cpu *cpuImpl) setDriverValues(powerProfile Profile) error {
 	if err := cpu.writeScalingMinFreq(minFreq); err != nil {
 		return fmt.Errorf("failed to set MinFreq value for cpu %d: %w", cpu.id, err)
 	}
+	if setSpeed != 0 {
+		if err := cpu.writeSetSpeed(setSpeed); err != nil {
+			return fmt.Errorf("failed to set SetSpeed value for cpu %d: %w", cpu.id, err)
+		}
+	}
 	return nil
 
 }
 
-func (cpu *cpuImpl) getFreqsToScale(profile Profile) (uint, uint) {
+// returns frequencies of the profile for the cpu core type, frequency levels take precedence if set
+func (cpu *cpuImpl) getFreqsToScale(profile Profile) (uint, uint, error) {
+	var minFreq, maxFreq uint
 	switch cpu.GetCore().GetType() {
 	case CpuTypeReferences.Pcore():
-		return profile.MinFreq(), profile.MaxFreq()
+		minFreq, maxFreq = profile.MinFreq(), profile.MaxFreq()
 	case CpuTypeReferences.Ecore():
-		return profile.EfficientMinFreq(), profile.EfficientMaxFreq()
+		minFreq, maxFreq = profile.EfficientMinFreq(), profile.EfficientMaxFreq()
 	default:
 		// something went wrong. default to these values which will likely result in error
-		return profile.MinFreq(), profile.MaxFreq()
+		minFreq, maxFreq = profile.MinFreq(), profile.MaxFreq()
+	}
+	minLevel, maxLevel := profile.FreqLevels()
+	var err error
+	if minLevel != "" {
+		if minFreq, err = cpu.resolveFreqLevel(minLevel); err != nil {
+			return 0, 0, err
+		}
+	}
+	if maxLevel != "" {
+		if maxFreq, err = cpu.resolveFreqLevel(maxLevel); err != nil {
+			return 0, 0, err
+		}
 	}
+	return minFreq, maxFreq, nil
 }
 
-func (cpu *cpuImpl) writeGovernorValue(governor string) error {
-	return os.WriteFile(filepath.Join(basePath, fmt.Sprint("cpu", cpu.id), scalingGovFile), []byte(governor), 0644)
+func (cpu *cpuImpl) getSetSpeedToScale(profile Profile) uint {
+	switch cpu.GetCore().GetType() {
+	case CpuTypeReferences.Pcore():
+		return profile.SetSpeed()
+	case CpuTypeReferences.Ecore():
+		return profile.EfficientSetSpeed()
+	default:
+		return profile.SetSpeed()
+	}
 }
-func (cpu *cpuImpl) writeEppValue(eppValue string) error {
-	return os.WriteFile(filepath.Join(basePath, fmt.Sprint("cpu", cpu.id), eppFile), []byte(eppValue), 0644)
+
+func (cpu *cpuImpl) writeSetSpeed(freq uint) error {
+	return writeSysfsValue(filepath.Join(basePath, fmt.Sprint("cpu", cpu.id), setSpeedFile), fmt.Sprint(freq))
 }
-func (cpu *cpuImpl) writeScalingMaxFreq(freq uint) error {
-	scalingFile := filepath.Join(basePath, fmt.Sprint("cpu", cpu.id), scalingMaxFile)
-	f, err := os.OpenFile(
-		scalingFile,
-		os.O_WRONLY|os.O_TRUNC|os.O_CREATE,
-		0644,
-	)
-	if err != nil {
-		return err
+
+// normalizeCpuFreq adjusts a frequency in kHz to one the driver can apply. drivers listing available frequencies get
+// the closest listed one, intel_pstate frequencies are rounded down to its bus ratio granularity
+func normalizeCpuFreq(freq uint, available []uint) uint {
+	if len(available) > 0 {
+		closest := available[0]
+		for _, f := range available[1:] {
+			if absDiff(f, freq) < absDiff(closest, freq) || (absDiff(f, freq) == absDiff(closest, freq) && f < closest) {
+				closest = f
+			}
+		}
+		return closest
+	}
+	switch featureList[FrequencyScalingFeature].driver {
+	case "intel_pstate", "intel_cpufreq":
+		return freq - (freq % pstateFreqGranularity)
 	}
-	defer f.Close()
+	return freq
+}
 
-	_, err = f.WriteString(fmt.Sprint(freq))
+// normalizes a profile frequency, logging adjustments
+func normalizeProfileFreq(profileName string, property string, freq uint, available []uint) uint {
+	normalized := normalizeCpuFreq(freq, available)
+	if normalized != freq {
+		log.Info("Frequency was normalized due to driver requirements", "profile", profileName, "property", property, "requested", freq, "normalized", normalized)
+	}
+	return normalized
+}
+
+func absDiff(a uint, b uint) uint {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
+// returns frequencies listed by the driver or nil if the driver does not list them
+func readAvailableFrequencies(cpuID uint) ([]uint, error) {
+	content, err := readStringFromFile(filepath.Join(basePath, fmt.Sprint("cpu", cpuID), availFreqsFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
 	if err != nil {
+		return nil, fmt.Errorf("failed to read available frequencies: %w", err)
+	}
+	freqs := make([]uint, 0)
+	for _, field := range strings.Fields(content) {
+		freq, err := strconv.Atoi(field)
+		if err != nil || freq < 0 {
+			return nil, fmt.Errorf("failed to parse available frequency %s", field)
+		}
+		freqs = append(freqs, uint(freq))
+	}
+	return freqs, nil
+}
+
+func (cpu *cpuImpl) writeGovernorValue(governor string) error {
+	changed, err := sysfsWrites.write(filepath.Join(basePath, fmt.Sprint("cpu", cpu.id), scalingGovFile), governor)
+	if err != nil || !changed {
 		return err
 	}
+	// governor change recreates tunables with their defaults and invalidates set speed
+	for _, dir := range governorTunableDirs(cpu.id, governor) {
+		sysfsWrites.forgetDir(dir)
+	}
+	sysfsWrites.forget(filepath.Join(basePath, fmt.Sprint("cpu", cpu.id), setSpeedFile))
+	// tunables of the previous governor stay in effect for other cpus sharing them
+	allErrors := make([]error, 0)
+	for _, tunableFile := range cpu.tunableFiles {
+		if isSharedTunable(cpu.id, tunableFile) {
+			allErrors = append(allErrors, cpu.releaseTunable(tunableFile))
+		}
+	}
+	cpu.tunableFiles = nil
+	return errors.Join(allErrors...)
+}
+
+// writes tunables after the governor was set so its directory exists, in order of their names. tunables written
+// before that the profile no longer sets are restored to their initial values
+func (cpu *cpuImpl) writeGovernorTunables(profile Profile) error {
+	tunables := profile.GovernorTunables()
+	names := make([]string, 0, len(tunables))
+	for name := range tunables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	written := make(map[string]bool, len(names))
+	for _, name := range names {
+		tunableFile, err := governorTunableFile(cpu.id, profile.Governor(), name)
+		if err != nil {
+			return err
+		}
+		value := fmt.Sprint(tunables[name])
+		if isSharedTunable(cpu.id, tunableFile) {
+			if err := sharedTunables.claim(tunableFile, cpu.id, cpu.tunablesOwner(), value); err != nil {
+				return err
+			}
+		}
+		if !cpu.hasTunableFile(tunableFile) {
+			cpu.tunableFiles = append(cpu.tunableFiles, tunableFile)
+		}
+		// initial value has to be known before the first write
+		if _, err := sysfsWrites.initialValue(tunableFile); err != nil {
+			return fmt.Errorf("failed to read initial value of tunable %s: %w", name, err)
+		}
+		if err := writeSysfsValue(tunableFile, value); err != nil {
+			return err
+		}
+		written[tunableFile] = true
+	}
+
+	kept := make([]string, 0, len(written))
+	for _, tunableFile := range cpu.tunableFiles {
+		if written[tunableFile] {
+			kept = append(kept, tunableFile)
+			continue
+		}
+		if err := cpu.releaseTunable(tunableFile); err != nil {
+			return err
+		}
+	}
+	cpu.tunableFiles = kept
 	return nil
 }
-func (cpu *cpuImpl) writeScalingMinFreq(freq uint) error {
-	scalingFile := filepath.Join(basePath, fmt.Sprint("cpu", cpu.id), scalingMinFile)
-	f, err := os.OpenFile(
-		scalingFile,
-		os.O_WRONLY|os.O_TRUNC|os.O_CREATE,
-		0644,
-	)
+
+// restores initial value of a tunable the cpu no longer sets, shared tunables only once no other cpu sets them
+func (cpu *cpuImpl) releaseTunable(tunableFile string) error {
+	if isSharedTunable(cpu.id, tunableFile) && !sharedTunables.release(tunableFile, cpu.id) {
+		return nil
+	}
+	// directory of a governor no cpu uses anymore is removed by the kernel
+	if _, err := os.Stat(tunableFile); os.IsNotExist(err) {
+		return nil
+	}
+	initial, err := sysfsWrites.initialValue(tunableFile)
+	if err == nil {
+		err = writeSysfsValue(tunableFile, initial)
+	}
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to restore tunable %s: %w", filepath.Base(tunableFile), err)
 	}
-	defer f.Close()
+	return nil
+}
 
-	_, err = f.WriteString(fmt.Sprint(freq))
-	if err != nil {
-		return err
+func (cpu *cpuImpl) hasTunableFile(tunableFile string) bool {
+	for _, file := range cpu.tunableFiles {
+		if file == tunableFile {
+			return true
+		}
 	}
+	return false
+}
+
+// identifies what sets tunables of the cpu, a cpu level profile or the profile of its pool
+func (cpu *cpuImpl) tunablesOwner() string {
+	if cpu.powerProfile != nil || cpu.pool == nil {
+		return fmt.Sprint("cpu ", cpu.id)
+	}
+	return "pool " + cpu.pool.Name()
+}
+
+// tunables outside of the cpu directory are in the system wide governor directory shared by all cpus
+func isSharedTunable(cpuID uint, tunableFile string) bool {
+	return !strings.HasPrefix(tunableFile, filepath.Join(basePath, fmt.Sprint("cpu", cpuID))+string(filepath.Separator))
+}
+
+// tunableClaims tracks values cpus set to tunables shared by all cpus. a shared tunable holds a single value,
+// so cpus whose tunables are set by different pools or cpu level profiles have to agree on it
+type tunableClaims struct {
+	mutex  sync.Mutex
+	claims map[string]map[uint]tunableClaim
+}
+
+type tunableClaim struct {
+	owner string
+	value string
+}
+
+var sharedTunables = &tunableClaims{claims: map[string]map[uint]tunableClaim{}}
+
+// claim records the value a cpu sets, fails if a different owner set another value
+func (c *tunableClaims) claim(tunableFile string, cpuID uint, owner string, value string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	ids := make([]uint, 0, len(c.claims[tunableFile]))
+	for id := range c.claims[tunableFile] {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		other := c.claims[tunableFile][id]
+		if id != cpuID && other.owner != owner && other.value != value {
+			return fmt.Errorf("tunable %s is shared by all cpus and set to %s by %s", filepath.Base(tunableFile), other.value, other.owner)
+		}
+	}
+	if c.claims[tunableFile] == nil {
+		c.claims[tunableFile] = map[uint]tunableClaim{}
+	}
+	c.claims[tunableFile][cpuID] = tunableClaim{owner: owner, value: value}
 	return nil
 }
+
+// release drops the claim of a cpu, returns true if no cpu sets the tunable anymore
+func (c *tunableClaims) release(tunableFile string, cpuID uint) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.claims[tunableFile], cpuID)
+	if len(c.claims[tunableFile]) > 0 {
+		return false
+	}
+	delete(c.claims, tunableFile)
+	return true
+}
+
+func (c *tunableClaims) reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.claims = map[string]map[uint]tunableClaim{}
+}
+
+// returns path of a governor tunable of a cpu, per policy location takes precedence
+func governorTunableFile(cpuID uint, governor string, tunable string) (string, error) {
+	for _, dir := range governorTunableDirs(cpuID, governor) {
+		tunableFile := filepath.Join(dir, tunable)
+		if _, err := os.Stat(tunableFile); err == nil {
+			return tunableFile, nil
+		}
+	}
+	return "", fmt.Errorf("tunable %s of %s governor not found", tunable, governor)
+}
+
+func governorTunableDirs(cpuID uint, governor string) []string {
+	return []string{
+		filepath.Join(basePath, fmt.Sprint("cpu", cpuID), governorTunablesDir, governor),
+		filepath.Join(basePath, governorTunablesDir, governor),
+	}
+}
+
+// lists tunables exposed by the governor on cpu0 or, if the governor is not in use, tunables known for it
+func availableGovernorTunables(governor string) ([]string, error) {
+	for _, dir := range governorTunableDirs(0, governor) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		tunables := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				tunables = append(tunables, entry.Name())
+			}
+		}
+		return tunables, nil
+	}
+	tunables, known := knownGovernorTunables[governor]
+	if !known {
+		return nil, fmt.Errorf("%s governor has no tunables", governor)
+	}
+	return tunables, nil
+}
+
+func (cpu *cpuImpl) writeEppValue(eppValue string) error {
+	return writeSysfsValue(filepath.Join(basePath, fmt.Sprint("cpu", cpu.id), eppFile), eppValueToWrite(eppValue))
+}
+func (cpu *cpuImpl) writeScalingMaxFreq(freq uint) error {
+	return writeSysfsValue(filepath.Join(basePath, fmt.Sprint("cpu", cpu.id), scalingMaxFile), fmt.Sprint(freq))
+}
+func (cpu *cpuImpl) writeScalingMinFreq(freq uint) error {
+	return writeSysfsValue(filepath.Join(basePath, fmt.Sprint("cpu", cpu.id), scalingMinFile), fmt.Sprint(freq))
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -15,6 +16,7 @@ func TestIsScalingDriverSupported(t *testing.T) {
 	assert.True(t, isScalingDriverSupported("intel_pstate"))
 	assert.True(t, isScalingDriverSupported("intel_cpufreq"))
 	assert.True(t, isScalingDriverSupported("acpi-cpufreq"))
+	assert.True(t, isScalingDriverSupported("amd-pstate-epp"))
 }
 func TestPreChecksScalingDriver(t *testing.T) {
 	var pStates featureStatus
@@ -187,3 +189,287 @@ func TestCoreImpl_setPstatsValues(t *testing.T) {
 	eppFileContent, _ = os.ReadFile(filepath.Join(basePath, "cpu0", eppFile))
 	assert.Equal(t, eppToSet, string(eppFileContent))
 }
+
+func TestCpuImpl_writeGovernorTunables(t *testing.T) {
+	defer setupCpuScalingTests(map[string]map[string]string{"cpu0": {}, "cpu1": {}})()
+	// per policy directory on cpu0, system wide directory used by cpu1
+	perPolicyDir := filepath.Join(basePath, "cpu0", governorTunablesDir, cpuPolicySchedutil)
+	globalDir := filepath.Join(basePath, governorTunablesDir, cpuPolicySchedutil)
+	for _, dir := range []string{perPolicyDir, globalDir} {
+		assert.NoError(t, os.MkdirAll(dir, os.ModePerm))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, "rate_limit_us"), []byte("1000"), 0644))
+	}
+	profile := &profileImpl{governor: cpuPolicySchedutil, governorTunables: map[string]uint{"rate_limit_us": 500}}
+
+	assert.NoError(t, (&cpuImpl{id: 0}).writeGovernorTunables(profile))
+	value, _ := readUintFromFile(filepath.Join(perPolicyDir, "rate_limit_us"))
+	assert.Equal(t, uint(500), value)
+	value, _ = readUintFromFile(filepath.Join(globalDir, "rate_limit_us"))
+	assert.Equal(t, uint(1000), value)
+
+	assert.NoError(t, (&cpuImpl{id: 1}).writeGovernorTunables(profile))
+	value, _ = readUintFromFile(filepath.Join(globalDir, "rate_limit_us"))
+	assert.Equal(t, uint(500), value)
+
+	profile.governorTunables["missing"] = 1
+	assert.ErrorContains(t, (&cpuImpl{id: 1}).writeGovernorTunables(profile), "tunable missing of schedutil governor not found")
+}
+
+func TestCpuImpl_writeGovernorTunablesOrder(t *testing.T) {
+	defer setupCpuScalingTests(map[string]map[string]string{"cpu0": {}})()
+	dir := filepath.Join(basePath, "cpu0", governorTunablesDir, cpuPolicyOndemand)
+	assert.NoError(t, os.MkdirAll(dir, os.ModePerm))
+	for _, name := range knownGovernorTunables[cpuPolicyOndemand] {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("0"), 0644))
+	}
+	written := []string{}
+	origWriteFile := writeSysfsFile
+	defer func() { writeSysfsFile = origWriteFile }()
+	writeSysfsFile = func(name string, data []byte, perm os.FileMode) error {
+		written = append(written, filepath.Base(name))
+		return origWriteFile(name, data, perm)
+	}
+
+	profile := &profileImpl{governor: cpuPolicyOndemand, governorTunables: map[string]uint{
+		"up_threshold": 80, "sampling_rate": 10000, "io_is_busy": 1, "powersave_bias": 0,
+	}}
+	assert.NoError(t, (&cpuImpl{id: 0}).writeGovernorTunables(profile))
+	assert.Equal(t, []string{"io_is_busy", "powersave_bias", "sampling_rate", "up_threshold"}, written)
+}
+
+func TestCpuImpl_writeGovernorTunablesRestore(t *testing.T) {
+	defer setupCpuScalingTests(map[string]map[string]string{"cpu0": {"governor": cpuPolicyOndemand}})()
+	dir := filepath.Join(basePath, "cpu0", governorTunablesDir, cpuPolicyOndemand)
+	assert.NoError(t, os.MkdirAll(dir, os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "up_threshold"), []byte("95\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "sampling_rate"), []byte("4000"), 0644))
+	cpu := &cpuImpl{id: 0}
+	tunable := func(name string) uint {
+		value, _ := readUintFromFile(filepath.Join(dir, name))
+		return value
+	}
+
+	both := &profileImpl{governor: cpuPolicyOndemand, governorTunables: map[string]uint{"up_threshold": 80, "sampling_rate": 10000}}
+	assert.NoError(t, cpu.writeGovernorTunables(both))
+	assert.Equal(t, uint(80), tunable("up_threshold"))
+	assert.Equal(t, uint(10000), tunable("sampling_rate"))
+
+	// tunable no longer set by the profile gets its initial value back
+	assert.NoError(t, cpu.writeGovernorTunables(&profileImpl{governor: cpuPolicyOndemand, governorTunables: map[string]uint{"sampling_rate": 20000}}))
+	assert.Equal(t, uint(95), tunable("up_threshold"))
+	assert.Equal(t, uint(20000), tunable("sampling_rate"))
+
+	assert.NoError(t, cpu.writeGovernorTunables(&profileImpl{governor: cpuPolicyOndemand}))
+	assert.Equal(t, uint(95), tunable("up_threshold"))
+	assert.Equal(t, uint(4000), tunable("sampling_rate"))
+	assert.Empty(t, cpu.tunableFiles)
+
+	// governor switch recreates tunables with defaults, nothing is restored afterwards
+	assert.NoError(t, cpu.writeGovernorTunables(both))
+	assert.NoError(t, cpu.writeGovernorValue(cpuPolicySchedutil))
+	assert.Empty(t, cpu.tunableFiles)
+	assert.NoError(t, cpu.writeGovernorTunables(&profileImpl{governor: cpuPolicySchedutil}))
+	assert.Equal(t, uint(80), tunable("up_threshold"))
+}
+
+func TestCpuImpl_writeGovernorTunablesSharedByPools(t *testing.T) {
+	defer setupCpuScalingTests(map[string]map[string]string{
+		"cpu0": {"governor": cpuPolicyOndemand},
+		"cpu1": {"governor": cpuPolicyOndemand},
+	})()
+	// acpi-cpufreq keeps tunables in the system wide directory
+	dir := filepath.Join(basePath, governorTunablesDir, cpuPolicyOndemand)
+	assert.NoError(t, os.MkdirAll(dir, os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "up_threshold"), []byte("95"), 0644))
+	tunable := func() uint {
+		value, _ := readUintFromFile(filepath.Join(dir, "up_threshold"))
+		return value
+	}
+	poolA := &poolImpl{name: "a"}
+	poolB := &poolImpl{name: "b"}
+	cpu0 := &cpuImpl{id: 0, pool: poolA}
+	cpu1 := &cpuImpl{id: 1, pool: poolB}
+	threshold := func(value uint) Profile {
+		return &profileImpl{governor: cpuPolicyOndemand, governorTunables: map[string]uint{"up_threshold": value}}
+	}
+	none := &profileImpl{governor: cpuPolicyOndemand}
+
+	assert.NoError(t, cpu0.writeGovernorTunables(threshold(80)))
+	assert.Equal(t, uint(80), tunable())
+
+	// other pool cannot set a different value
+	assert.ErrorContains(t, cpu1.writeGovernorTunables(threshold(90)), "tunable up_threshold is shared by all cpus and set to 80 by pool a")
+	assert.Equal(t, uint(80), tunable())
+	assert.NoError(t, cpu1.writeGovernorTunables(threshold(80)))
+
+	// initial value is restored once neither pool sets the tunable
+	assert.NoError(t, cpu0.writeGovernorTunables(none))
+	assert.Equal(t, uint(80), tunable())
+	assert.NoError(t, cpu1.writeGovernorTunables(none))
+	assert.Equal(t, uint(95), tunable())
+
+	// cpus of the same pool change the value one by one
+	cpu1.pool = poolA
+	assert.NoError(t, cpu0.writeGovernorTunables(threshold(80)))
+	assert.NoError(t, cpu1.writeGovernorTunables(threshold(80)))
+	assert.NoError(t, cpu0.writeGovernorTunables(threshold(70)))
+	assert.NoError(t, cpu1.writeGovernorTunables(threshold(70)))
+	assert.Equal(t, uint(70), tunable())
+
+	// switching governor releases the tunable
+	assert.NoError(t, cpu0.writeGovernorValue(cpuPolicySchedutil))
+	assert.Equal(t, uint(70), tunable())
+	assert.NoError(t, cpu1.writeGovernorValue(cpuPolicySchedutil))
+	assert.Equal(t, uint(95), tunable())
+}
+
+func TestCpuImpl_setDriverValuesSetSpeed(t *testing.T) {
+	defer setupCpuScalingTests(map[string]map[string]string{
+		"cpu0": {"max": "3000000", "min": "1000000"},
+		"cpu1": {"max": "2000000", "min": "800000"},
+	})()
+	coreTypes = CoreTypeList{&CpuFrequencySet{min: 1_000_000, max: 3_000_000}, &CpuFrequencySet{min: 800_000, max: 2_000_000}}
+	CpuTypeReferences = supportedCores{pcore: 0, ecore: 1}
+	profile := &profileImpl{
+		governor:          cpuPolicyUserspace,
+		min:               1_000_000,
+		max:               3_000_000,
+		efficientMin:      800_000,
+		efficientMax:      2_000_000,
+		setSpeed:          2_500_000,
+		efficientSetSpeed: 1_500_000,
+	}
+	pcpu := &cpuImpl{id: 0, core: &cpuCore{coreType: 0}}
+	ecpu := &cpuImpl{id: 1, core: &cpuCore{coreType: 1}}
+
+	assert.NoError(t, pcpu.setDriverValues(profile))
+	assert.NoError(t, ecpu.setDriverValues(profile))
+	value, _ := readCpuUintProperty(0, setSpeedFile)
+	assert.Equal(t, uint(2_500_000), value)
+	value, _ = readCpuUintProperty(1, setSpeedFile)
+	assert.Equal(t, uint(1_500_000), value)
+
+	// set speed outside of the range resolved from levels
+	profile.minLevel, profile.maxLevel = FreqLevelMin, "70%"
+	err := pcpu.setDriverValues(profile)
+	assert.ErrorIs(t, err, ErrOutOfRange)
+	assert.EqualError(t, err, "set speed 2500000 is out of range 1000000-2400000")
+	assert.NoError(t, ecpu.setDriverValues(profile))
+	profile.minLevel, profile.maxLevel = "", ""
+
+	// nothing written without set speed
+	profile.setSpeed = 0
+	assert.NoError(t, os.Remove(filepath.Join(basePath, "cpu0", setSpeedFile)))
+	assert.NoError(t, pcpu.setDriverValues(profile))
+	assert.NoFileExists(t, filepath.Join(basePath, "cpu0", setSpeedFile))
+}
+
+func TestValidateEpp(t *testing.T) {
+	origDriver := featureList[FrequencyScalingFeature].driver
+	defer func() {
+		featureList[FrequencyScalingFeature].driver = origDriver
+		featureList[EPPFeature].err = uninitialisedErr
+		availableEpps = nil
+	}()
+
+	// only governor compatibility checked if epp is not supported
+	assert.NoError(t, validateEpp("anything", cpuPolicyPowersave))
+	assert.NoError(t, validateEpp("", cpuPolicyPerformance))
+	assert.ErrorContains(t, validateEpp("power", cpuPolicyPerformance), "only 'performance' epp")
+
+	featureList[EPPFeature].err = nil
+	availableEpps = []string{"default", "performance", "balance_performance", "balance_power", "power"}
+	assert.NoError(t, validateEpp("balance_power", cpuPolicyPowersave))
+	err := validateEpp("balance-power", cpuPolicyPowersave)
+	assert.ErrorContains(t, err, "epp balance-power is not available")
+	var notAvailable *NotAvailableError
+	assert.ErrorAs(t, err, &notAvailable)
+	assert.Equal(t, availableEpps, notAvailable.Available)
+
+	// numeric values
+	featureList[FrequencyScalingFeature].driver = "amd-pstate-epp"
+	err = validateEpp("64", cpuPolicyPowersave)
+	assert.ErrorIs(t, err, ErrNotAvailable)
+	assert.ErrorContains(t, err, "not available with amd-pstate-epp driver")
+	featureList[FrequencyScalingFeature].driver = "intel_pstate"
+	assert.NoError(t, validateEpp("64", cpuPolicyPowersave))
+	assert.NoError(t, validateEpp("0", cpuPolicyPerformance))
+	assert.ErrorContains(t, validateEpp("64", cpuPolicyPerformance), "only 'performance' epp")
+	assert.ErrorContains(t, validateEpp("256", cpuPolicyPowersave), "out of range 0-255")
+	assert.ErrorIs(t, validateEpp("256", cpuPolicyPowersave), ErrOutOfRange)
+}
+
+func TestEppValueToWrite(t *testing.T) {
+	origDriver := featureList[FrequencyScalingFeature].driver
+	defer func() { featureList[FrequencyScalingFeature].driver = origDriver }()
+
+	featureList[FrequencyScalingFeature].driver = "intel_pstate"
+	assert.Equal(t, "0", eppValueToWrite("performance"))
+	assert.Equal(t, "128", eppValueToWrite("balance_performance"))
+	assert.Equal(t, "191", eppValueToWrite("balance_power"))
+	assert.Equal(t, "255", eppValueToWrite("power"))
+	assert.Equal(t, "default", eppValueToWrite("default"))
+	assert.Equal(t, "77", eppValueToWrite("77"))
+
+	featureList[FrequencyScalingFeature].driver = "amd-pstate-epp"
+	assert.Equal(t, "balance_power", eppValueToWrite("balance_power"))
+}
+
+func Test_initEppAvailablePreferences(t *testing.T) {
+	defer setupCpuScalingTests(map[string]map[string]string{"cpu0": {"epp": "default"}})()
+	defer func() { availableEpps = nil }()
+	assert.NoError(t, initEpp().err)
+	assert.Nil(t, availableEpps)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(basePath, "cpu0", availEppFile), []byte("default performance balance_performance balance_power power \n"), 0644))
+	assert.NoError(t, initEpp().err)
+	assert.Equal(t, []string{"default", "performance", "balance_performance", "balance_power", "power"}, availableEpps)
+}
+
+func TestCpuImpl_SetPowerProfile(t *testing.T) {
+	const (
+		maxDefault = 9990
+		minDefault = 1000
+		cpuMax     = 5000
+		poolMax    = 8000
+	)
+	cpu := &cpuImpl{id: 0, mutex: &sync.Mutex{}, core: &cpuCore{coreType: 0}}
+	// p-states not supported
+	assert.ErrorIs(t, cpu.SetPowerProfile(&profileImpl{}), uninitialisedErr)
+	assert.ErrorIs(t, cpu.ClearPowerProfile(), uninitialisedErr)
+
+	defer setupCpuScalingTests(map[string]map[string]string{
+		"cpu0": {
+			"max": fmt.Sprint(maxDefault),
+			"min": fmt.Sprint(minDefault),
+		},
+	})()
+	coreTypes = CoreTypeList{&CpuFrequencySet{min: minDefault, max: maxDefault}}
+
+	pool := new(poolMock)
+	pool.On("GetPowerProfile").Return(&profileImpl{max: poolMax, min: minDefault})
+	pool.On("poolMutex").Return(&sync.Mutex{})
+	cpu.pool = pool
+
+	assert.Error(t, cpu.SetPowerProfile(nil))
+
+	// cpu profile takes precedence over pool profile
+	cpuProfile := &profileImpl{max: cpuMax, min: minDefault}
+	assert.NoError(t, cpu.SetPowerProfile(cpuProfile))
+	assert.Equal(t, cpuProfile, cpu.getPowerProfile())
+	maxFreq, _ := readCpuUintProperty(0, scalingMaxFile)
+	assert.Equal(t, uint(cpuMax), maxFreq)
+	pool.AssertNotCalled(t, "GetPowerProfile")
+
+	// cpu profile stays in effect when consolidating
+	assert.NoError(t, cpu.consolidate())
+	maxFreq, _ = readCpuUintProperty(0, scalingMaxFile)
+	assert.Equal(t, uint(cpuMax), maxFreq)
+
+	// pool profile is applied after clearing
+	assert.NoError(t, cpu.ClearPowerProfile())
+	assert.Nil(t, cpu.getPowerProfile())
+	maxFreq, _ = readCpuUintProperty(0, scalingMaxFile)
+	assert.Equal(t, uint(poolMax), maxFreq)
+	pool.AssertExpectations(t)
+}
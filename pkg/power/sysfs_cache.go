@@ -0,0 +1,132 @@
+package power
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// sysfsCache remembers the last value written to each sysfs file so consolidation only writes files whose
+// desired value changed. rewriting unchanged values costs a syscall per file and resets internal state of governors
+type sysfsCache struct {
+	mutex  sync.Mutex
+	values map[string]string
+	// serialize writers of the same file, kept across resets so a writer never holds a stale lock
+	fileLocks map[string]*sync.Mutex
+	// content of files read by initialValue before they were first written
+	initValues map[string]string
+	// read the file before skipping a write, detects values changed outside the library
+	verify bool
+}
+
+var (
+	sysfsWrites = &sysfsCache{values: map[string]string{}}
+	// set by LibConfig
+	verifySysfsWrites = false
+	// defined as var so can be mocked by the unit test
+	writeSysfsFile = os.WriteFile
+)
+
+// writeSysfsValue writes value to a file unless it is known to hold it already
+func writeSysfsValue(filePath string, value string) error {
+	_, err := sysfsWrites.write(filePath, value)
+	return err
+}
+
+// write returns true if the file was written. the file lock is held from the comparison until the value is cached,
+// so concurrent writers of the same file cannot skip a write based on a value being replaced
+func (c *sysfsCache) write(filePath string, value string) (bool, error) {
+	fileLock := c.fileLock(filePath)
+	fileLock.Lock()
+	defer fileLock.Unlock()
+
+	c.mutex.Lock()
+	cached, exists := c.values[filePath]
+	verify := c.verify
+	c.mutex.Unlock()
+	if exists && cached == value && (!verify || c.holds(filePath, value)) {
+		return false, nil
+	}
+	if err := writeSysfsFile(filePath, []byte(value), 0644); err != nil {
+		// state of the file is unknown after a failed write
+		c.forget(filePath)
+		return false, newSysfsWriteError(filePath, value, err)
+	}
+	c.mutex.Lock()
+	c.values[filePath] = value
+	c.mutex.Unlock()
+	return true, nil
+}
+
+func (c *sysfsCache) fileLock(filePath string) *sync.Mutex {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.fileLocks == nil {
+		c.fileLocks = map[string]*sync.Mutex{}
+	}
+	fileLock, exists := c.fileLocks[filePath]
+	if !exists {
+		fileLock = &sync.Mutex{}
+		c.fileLocks[filePath] = fileLock
+	}
+	return fileLock
+}
+
+// initialValue returns content of the file when it was first requested, callers request it before writing the file
+func (c *sysfsCache) initialValue(filePath string) (string, error) {
+	fileLock := c.fileLock(filePath)
+	fileLock.Lock()
+	defer fileLock.Unlock()
+
+	c.mutex.Lock()
+	value, exists := c.initValues[filePath]
+	c.mutex.Unlock()
+	if exists {
+		return value, nil
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	value = strings.TrimSpace(string(content))
+	c.mutex.Lock()
+	if c.initValues == nil {
+		c.initValues = map[string]string{}
+	}
+	c.initValues[filePath] = value
+	c.mutex.Unlock()
+	return value, nil
+}
+
+func (c *sysfsCache) holds(filePath string, value string) bool {
+	content, err := os.ReadFile(filePath)
+	return err == nil && strings.TrimSpace(string(content)) == value
+}
+
+func (c *sysfsCache) forget(filePath string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.values, filePath)
+}
+
+// forgetDir drops cached values of all files in a directory, used when the kernel recreates it
+func (c *sysfsCache) forgetDir(dir string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	prefix := filepath.Clean(dir) + string(filepath.Separator)
+	for filePath := range c.values {
+		if strings.HasPrefix(filePath, prefix) {
+			delete(c.values, filePath)
+		}
+	}
+}
+
+// reset drops all cached and initial values, next consolidation writes every file
+func (c *sysfsCache) reset(verify bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.values = map[string]string{}
+	c.initValues = map[string]string{}
+	c.verify = verify
+}
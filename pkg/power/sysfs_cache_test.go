@@ -0,0 +1,167 @@
+package power
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSysfsCache_write(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "value")
+	cache := &sysfsCache{values: map[string]string{}}
+
+	written, err := cache.write(file, "1")
+	assert.NoError(t, err)
+	assert.True(t, written)
+
+	// value changed outside of the library is not detected without verification
+	assert.NoError(t, os.WriteFile(file, []byte("2"), 0644))
+	written, err = cache.write(file, "1")
+	assert.NoError(t, err)
+	assert.False(t, written)
+	content, _ := os.ReadFile(file)
+	assert.Equal(t, "2", string(content))
+
+	written, err = cache.write(file, "3")
+	assert.NoError(t, err)
+	assert.True(t, written)
+	content, _ = os.ReadFile(file)
+	assert.Equal(t, "3", string(content))
+
+	cache.reset(true)
+	_, err = cache.write(file, "1")
+	assert.NoError(t, err)
+	// matching content is not rewritten
+	written, err = cache.write(file, "1")
+	assert.NoError(t, err)
+	assert.False(t, written)
+	assert.NoError(t, os.WriteFile(file, []byte("2\n"), 0644))
+	written, err = cache.write(file, "1")
+	assert.NoError(t, err)
+	assert.True(t, written)
+	content, _ = os.ReadFile(file)
+	assert.Equal(t, "1", string(content))
+
+	// failed write is not cached
+	missing := filepath.Join(dir, "missing", "value")
+	_, err = cache.write(missing, "1")
+	assert.Error(t, err)
+	assert.NotContains(t, cache.values, missing)
+}
+
+func TestSysfsCache_forgetDir(t *testing.T) {
+	cache := &sysfsCache{values: map[string]string{
+		"cpu0/cpufreq/schedutil/rate_limit_us": "100",
+		"cpu0/cpufreq/schedutil2/value":        "1",
+		"cpu0/cpufreq/scaling_governor":        "schedutil",
+	}}
+	cache.forgetDir("cpu0/cpufreq/schedutil")
+	assert.Equal(t, map[string]string{
+		"cpu0/cpufreq/schedutil2/value": "1",
+		"cpu0/cpufreq/scaling_governor": "schedutil",
+	}, cache.values)
+}
+
+func TestCpuImpl_setDriverValuesSkipsUnchanged(t *testing.T) {
+	defer setupCpuScalingTests(map[string]map[string]string{
+		"cpu0": {"max": "9999", "min": "999", "governor": cpuPolicyPowersave},
+	})()
+	coreTypes = CoreTypeList{&CpuFrequencySet{min: 999, max: 9999}}
+	cpu := &cpuImpl{id: 0, core: &cpuCore{}}
+	profile := &profileImpl{governor: cpuPolicySchedutil, min: 999, max: 5000}
+	tunablesDir := filepath.Join(basePath, "cpu0", governorTunablesDir, cpuPolicySchedutil)
+	assert.NoError(t, os.MkdirAll(tunablesDir, os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(tunablesDir, "rate_limit_us"), []byte("1000"), 0644))
+	profile.governorTunables = map[string]uint{"rate_limit_us": 500}
+
+	assert.NoError(t, cpu.setDriverValues(profile))
+	maxFreq, _ := readCpuUintProperty(0, scalingMaxFile)
+	assert.Equal(t, uint(5000), maxFreq)
+
+	// nothing is rewritten when consolidating the same profile
+	assert.NoError(t, os.WriteFile(filepath.Join(basePath, "cpu0", scalingMaxFile), []byte("7000"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(tunablesDir, "rate_limit_us"), []byte("1000"), 0644))
+	assert.NoError(t, cpu.setDriverValues(profile))
+	maxFreq, _ = readCpuUintProperty(0, scalingMaxFile)
+	assert.Equal(t, uint(7000), maxFreq)
+
+	// governor switch resets tunables, they are written again after switching back
+	assert.NoError(t, cpu.setDriverValues(&profileImpl{governor: cpuPolicyPowersave, min: 999, max: 5000}))
+	assert.NoError(t, cpu.setDriverValues(profile))
+	tunable, _ := readUintFromFile(filepath.Join(tunablesDir, "rate_limit_us"))
+	assert.Equal(t, uint(500), tunable)
+}
+
+func TestSysfsCache_initialValue(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "value")
+	assert.NoError(t, os.WriteFile(file, []byte("10\n"), 0644))
+	cache := &sysfsCache{values: map[string]string{}}
+
+	value, err := cache.initialValue(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "10", value)
+	_, err = cache.write(file, "20")
+	assert.NoError(t, err)
+	value, err = cache.initialValue(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "10", value)
+
+	// new instance starts from current content
+	cache.reset(false)
+	value, err = cache.initialValue(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "20", value)
+
+	_, err = cache.initialValue(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestSysfsCache_concurrentWriters(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "value")
+	cache := &sysfsCache{values: map[string]string{}}
+
+	// first writer is slow to finish its write, the second one starts writing in the meantime
+	firstWriting := make(chan struct{})
+	once := sync.Once{}
+	origWriteFile := writeSysfsFile
+	defer func() { writeSysfsFile = origWriteFile }()
+	writeSysfsFile = func(name string, data []byte, perm os.FileMode) error {
+		err := origWriteFile(name, data, perm)
+		if string(data) == "1" {
+			once.Do(func() {
+				close(firstWriting)
+				time.Sleep(50 * time.Millisecond)
+			})
+		}
+		return err
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, err := cache.write(file, "1")
+		assert.NoError(t, err)
+	}()
+	go func() {
+		defer wg.Done()
+		<-firstWriting
+		_, err := cache.write(file, "2")
+		assert.NoError(t, err)
+	}()
+	wg.Wait()
+
+	// cached value matches the file, so writing the first value again is not skipped
+	content, err := os.ReadFile(file)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", string(content))
+	assert.Equal(t, "2", cache.values[file])
+	written, err := cache.write(file, "1")
+	assert.NoError(t, err)
+	assert.True(t, written)
+}
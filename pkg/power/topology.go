@@ -1,5 +1,7 @@
 package power
 
+import "sync"
+
 const (
 	cpuTopologyDir = "topology/"
 	packageIdFile  = cpuTopologyDir + "physical_package_id"
@@ -28,6 +30,7 @@ type (
 	Topology interface {
 		topologyTypeObj
 		hasUncore
+		hasUncoreDomains
 		Packages() *[]Package
 		Package(id uint) Package
 	}
@@ -95,12 +98,16 @@ type (
 		uncore   Uncore
 		cpus     CpuList
 		dies     dieList
+		// uncore domains not associated with any die
+		domains []UncoreDomain
 	}
 	Package interface {
 		hasUncore
+		hasUncoreDomains
 		topologyTypeObj
 		Dies() *[]Die
 		Die(id uint) Die
+		addUncoreDomain(info uncoreDomainInfo)
 	}
 )
 
@@ -159,14 +166,21 @@ type (
 		parentSocket Package
 		id           uint
 		uncore       Uncore
-		cores        coreList
-		cpus         CpuList
+		// uncore requested by pools with cpus on this die
+		poolUncores map[Pool]Uncore
+		mutex       sync.Mutex
+		domains     []UncoreDomain
+		cores       coreList
+		cpus        CpuList
 	}
 	Die interface {
 		topologyTypeObj
 		hasUncore
+		hasUncoreDomains
 		Cores() *[]Core
 		Core(id uint) Core
+		setPoolUncore(pool Pool, uncore Uncore) error
+		addUncoreDomain(domain UncoreDomain)
 	}
 )
 
@@ -276,5 +290,8 @@ var discoverTopology = func() (Topology, error) {
 			return nil, err
 		}
 	}
+	if featureList.isFeatureIdSupported(UncoreFeature) {
+		topology.addUncoreDomains(uncoreDomainInfos)
+	}
 	return topology, nil
 }
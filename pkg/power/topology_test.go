@@ -23,6 +23,26 @@ func (m *mockCpuTopology) SetUncore(uncore Uncore) error {
 	return m.Called(uncore).Error(0)
 }
 
+func (m *mockCpuTopology) ReadUncore() ([]UncoreInfo, error) {
+	ret := m.Called()
+	if ret.Get(0) == nil {
+		return nil, ret.Error(1)
+	}
+	return ret.Get(0).([]UncoreInfo), ret.Error(1)
+}
+
+func (m *mockCpuTopology) uncoreSource() UncoreLevel {
+	return m.Called().Get(0).(UncoreLevel)
+}
+
+func (m *mockCpuTopology) UncoreDomains() []UncoreDomain {
+	ret := m.Called().Get(0)
+	if ret == nil {
+		return nil
+	}
+	return ret.([]UncoreDomain)
+}
+
 func (m *mockCpuTopology) applyUncore() error {
 	return m.Called().Error(0)
 }
@@ -94,6 +114,30 @@ func (m *mockCpuPackage) SetUncore(uncore Uncore) error {
 	return m.Called(uncore).Error(0)
 }
 
+func (m *mockCpuPackage) ReadUncore() ([]UncoreInfo, error) {
+	ret := m.Called()
+	if ret.Get(0) == nil {
+		return nil, ret.Error(1)
+	}
+	return ret.Get(0).([]UncoreInfo), ret.Error(1)
+}
+
+func (m *mockCpuPackage) uncoreSource() UncoreLevel {
+	return m.Called().Get(0).(UncoreLevel)
+}
+
+func (m *mockCpuPackage) UncoreDomains() []UncoreDomain {
+	ret := m.Called().Get(0)
+	if ret == nil {
+		return nil
+	}
+	return ret.([]UncoreDomain)
+}
+
+func (m *mockCpuPackage) addUncoreDomain(info uncoreDomainInfo) {
+	m.Called(info)
+}
+
 func (m *mockCpuPackage) applyUncore() error {
 	return m.Called().Error(0)
 }
@@ -165,6 +209,34 @@ func (m *mockCpuDie) SetUncore(uncore Uncore) error {
 	return m.Called(uncore).Error(0)
 }
 
+func (m *mockCpuDie) addUncoreDomain(domain UncoreDomain) {
+	m.Called(domain)
+}
+
+func (m *mockCpuDie) setPoolUncore(pool Pool, uncore Uncore) error {
+	return m.Called(pool, uncore).Error(0)
+}
+
+func (m *mockCpuDie) ReadUncore() ([]UncoreInfo, error) {
+	ret := m.Called()
+	if ret.Get(0) == nil {
+		return nil, ret.Error(1)
+	}
+	return ret.Get(0).([]UncoreInfo), ret.Error(1)
+}
+
+func (m *mockCpuDie) uncoreSource() UncoreLevel {
+	return m.Called().Get(0).(UncoreLevel)
+}
+
+func (m *mockCpuDie) UncoreDomains() []UncoreDomain {
+	ret := m.Called().Get(0)
+	if ret == nil {
+		return nil
+	}
+	return ret.([]UncoreDomain)
+}
+
 func (m *mockCpuDie) applyUncore() error {
 	return m.Called().Error(0)
 }
@@ -269,6 +341,8 @@ func (m *mockCpuCore) getID() uint {
 func setupTopologyTest(cpufiles map[string]map[string]string) func() {
 	origBasePath := basePath
 	basePath = "testing/cpus"
+	// fake files are recreated, cached values of previous tests do not apply
+	sysfsWrites.reset(false)
 
 	// backup pointer to function that gets all CPUs
 	// replace it with our controlled function
@@ -2,6 +2,8 @@ package power
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path"
@@ -12,32 +14,68 @@ const (
 	uncoreKmodName = "intel_uncore_frequency"
 	uncoreDirName  = "intel_uncore_frequency"
 
-	uncorePathFmt         = uncoreDirName + "/package_%02d_die_%02d"
 	uncoreInitMaxFreqFile = "initial_max_freq_khz"
 	uncoreInitMinFreqFile = "initial_min_freq_khz"
 	uncoreMaxFreqFile     = "max_freq_khz"
 	uncoreMinFreqFile     = "min_freq_khz"
+	uncoreCurrentFreqFile = "current_freq_khz"
+
+	// efficiency latency control, available in recent versions of the driver
+	uncoreElcLowThresholdFile  = "elc_low_threshold_percent"
+	uncoreElcHighThresholdFile = "elc_high_threshold_percent"
+	uncoreElcHighEnableFile    = "elc_high_threshold_enable"
+	uncoreElcFloorFreqFile     = "elc_floor_freq_khz"
 )
 
 type (
 	uncoreFreq struct {
 		min uint
 		max uint
+		// optional, nil restores initial ELC settings of the domain
+		elc *UncoreElc
 	}
 	Uncore interface {
-		write(pkgID, dieID uint) error
+		write(dir string) error
+		limits() (uint, uint)
+		elcParams() *UncoreElc
+	}
+	// UncoreElc stores uncore efficiency latency control settings.
+	// below the low threshold of utilization uncore frequency is not reduced below the floor frequency,
+	// above the high threshold, if enabled, uncore frequency is increased
+	UncoreElc struct {
+		LowThresholdPercent  uint
+		HighThresholdPercent uint
+		HighThresholdEnable  bool
+		// kHz
+		FloorFreq uint
 	}
 )
 
+// set during feature initialisation if the driver exposes ELC controls
+var uncoreElcSupported = false
+
+// UncorePoolPolicy determines how uncore frequencies requested by multiple pools sharing a die are combined
+type UncorePoolPolicy uint
+
+const (
+	// UncorePoolPolicyMax applies the highest of requested min and max frequencies
+	UncorePoolPolicyMax UncorePoolPolicy = iota
+	// UncorePoolPolicyMin applies the lowest of requested min and max frequencies
+	UncorePoolPolicyMin
+)
+
+// policy used to arbitrate pool uncore requests, can be set with LibConfig
+var uncorePoolPolicy = UncorePoolPolicyMax
+
 func NewUncore(minFreq uint, maxFreq uint) (Uncore, error) {
 	if !featureList.isFeatureIdSupported(UncoreFeature) {
 		return nil, featureList.getFeatureIdError(UncoreFeature)
 	}
 	if minFreq < defaultUncore.min {
-		return nil, fmt.Errorf("specified Min frequency is lower than %d kHZ allowed by the hardware", defaultUncore.min)
+		return nil, &OutOfRangeError{Setting: "uncore min frequency", Value: minFreq, Min: defaultUncore.min, Max: defaultUncore.max}
 	}
 	if maxFreq > defaultUncore.max {
-		return nil, fmt.Errorf("specified Max frequency is higher than %d kHz allowed by the hardware", defaultUncore.max)
+		return nil, &OutOfRangeError{Setting: "uncore max frequency", Value: maxFreq, Min: defaultUncore.min, Max: defaultUncore.max}
 	}
 	if maxFreq < minFreq {
 		return nil, fmt.Errorf("max freq cannot be lower than min")
@@ -54,21 +92,71 @@ func NewUncore(minFreq uint, maxFreq uint) (Uncore, error) {
 	return &uncoreFreq{min: normalizedMin, max: normalizedMax}, nil
 }
 
-func (u *uncoreFreq) write(pkgId, dieId uint) error {
-	if err := os.WriteFile(
-		path.Join(basePath, fmt.Sprintf(uncorePathFmt, pkgId, dieId), uncoreMaxFreqFile),
-		[]byte(fmt.Sprint(u.max)),
-		0644,
-	); err != nil {
+// NewUncoreWithElc creates an Uncore object that additionally applies efficiency latency control settings
+func NewUncoreWithElc(minFreq uint, maxFreq uint, elc UncoreElc) (Uncore, error) {
+	uncore, err := NewUncore(minFreq, maxFreq)
+	if err != nil {
+		return nil, err
+	}
+	if !uncoreElcSupported {
+		return nil, fmt.Errorf("uncore efficiency latency control is not supported by the driver")
+	}
+	if elc.LowThresholdPercent > 100 {
+		return nil, &OutOfRangeError{Setting: "ELC low threshold", Value: elc.LowThresholdPercent, Min: 0, Max: 100}
+	}
+	if elc.HighThresholdPercent > 100 {
+		return nil, &OutOfRangeError{Setting: "ELC high threshold", Value: elc.HighThresholdPercent, Min: 0, Max: 100}
+	}
+	if elc.LowThresholdPercent > elc.HighThresholdPercent {
+		return nil, fmt.Errorf("ELC low threshold cannot be higher than high threshold")
+	}
+	normalizedFloor := normalizeUncoreFreq(elc.FloorFreq)
+	if normalizedFloor != elc.FloorFreq {
+		log.Info("Uncore ELC floor frequency was normalized due to driver requirements", "requested", elc.FloorFreq, "normalized", normalizedFloor)
+		elc.FloorFreq = normalizedFloor
+	}
+	min, max := uncore.limits()
+	if elc.FloorFreq < min || elc.FloorFreq > max {
+		return nil, &OutOfRangeError{Setting: "ELC floor frequency", Value: elc.FloorFreq, Min: min, Max: max}
+	}
+	uncore.(*uncoreFreq).elc = &elc
+	return uncore, nil
+}
+
+func (u *uncoreFreq) limits() (uint, uint) {
+	return u.min, u.max
+}
+
+func (u *uncoreFreq) elcParams() *UncoreElc {
+	return u.elc
+}
+
+// write applies uncore to a domain directory relative to basePath
+func (u *uncoreFreq) write(dir string) error {
+	if err := writeSysfsValue(path.Join(basePath, dir, uncoreMaxFreqFile), fmt.Sprint(u.max)); err != nil {
 		return err
 	}
-	if err := os.WriteFile(
-		path.Join(basePath, fmt.Sprintf(uncorePathFmt, pkgId, dieId), uncoreMinFreqFile),
-		[]byte(fmt.Sprint(u.min)),
-		0644,
-	); err != nil {
+	if err := writeSysfsValue(path.Join(basePath, dir, uncoreMinFreqFile), fmt.Sprint(u.min)); err != nil {
 		return err
 	}
+	if u.elc == nil {
+		return nil
+	}
+	enable := "0"
+	if u.elc.HighThresholdEnable {
+		enable = "1"
+	}
+	// floor is written after min and max it has to be within, thresholds before the high threshold is enabled
+	for _, file := range []struct{ name, value string }{
+		{uncoreElcFloorFreqFile, fmt.Sprint(u.elc.FloorFreq)},
+		{uncoreElcLowThresholdFile, fmt.Sprint(u.elc.LowThresholdPercent)},
+		{uncoreElcHighThresholdFile, fmt.Sprint(u.elc.HighThresholdPercent)},
+		{uncoreElcHighEnableFile, enable},
+	} {
+		if err := writeSysfsValue(path.Join(basePath, dir, file.name), file.value); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -82,6 +170,7 @@ func initUncore() featureStatus {
 		name:     "Uncore frequency",
 		driver:   "N/A",
 		initFunc: initUncore,
+		details:  uncoreDetails,
 	}
 
 	if !checkKernelModuleLoaded(uncoreKmodName) {
@@ -99,22 +188,55 @@ func initUncore() featureStatus {
 		return feature
 	}
 
-	if value, err := readUncoreProperty(0, 0, uncoreInitMaxFreqFile); err != nil {
-		feature.err = fmt.Errorf("uncore feature error %w", fmt.Errorf("failed to determine init freq: %w", err))
+	domains, err := discoverUncoreDomains()
+	if err != nil {
+		feature.err = fmt.Errorf("uncore feature error %w", err)
 		return feature
-	} else {
-		defaultUncore.max = value
 	}
-	if value, err := readUncoreProperty(0, 0, uncoreInitMinFreqFile); err != nil {
-		feature.err = fmt.Errorf("uncore feature error %w", fmt.Errorf("failed to determine init freq: %w", err))
-		return feature
-	} else {
-		defaultUncore.min = value
+	uncoreDomainInfos = domains
+	if domains[0].tpmi {
+		feature.driver = "TPMI"
+	}
+	// uncore requested with NewUncore can be applied to any domain, so it has to be within limits of all of them
+	defaultUncore.min = domains[0].initMin
+	defaultUncore.max = domains[0].initMax
+	uncoreElcSupported = true
+	for _, domain := range domains {
+		if domain.initMin > defaultUncore.min {
+			defaultUncore.min = domain.initMin
+		}
+		if domain.initMax < defaultUncore.max {
+			defaultUncore.max = domain.initMax
+		}
+		if domain.initElc == nil {
+			uncoreElcSupported = false
+		}
 	}
 
 	return feature
 }
 
+func uncoreDetails() featureDetails {
+	paths := make([]string, len(uncoreDomainInfos))
+	domains := make([]map[string]any, len(uncoreDomainInfos))
+	for i, domain := range uncoreDomainInfos {
+		paths[i] = path.Join(basePath, domain.dir)
+		domains[i] = map[string]any{
+			"package": domain.pkgID,
+			"domain":  domain.domainID,
+			"min_khz": domain.initMin,
+			"max_khz": domain.initMax,
+		}
+	}
+	return featureDetails{
+		paths: paths,
+		capabilities: map[string]any{
+			"domains": domains,
+			"elc":     uncoreElcSupported,
+		},
+	}
+}
+
 func checkKernelModuleLoaded(module string) bool {
 	modulesFile, err := os.Open(kernelModulesFilePath)
 	if err != nil {
@@ -135,6 +257,8 @@ type hasUncore interface {
 	SetUncore(uncore Uncore) error
 	applyUncore() error
 	getEffectiveUncore() Uncore
+	// level of the hierarchy the effective uncore comes from
+	uncoreSource() UncoreLevel
 }
 
 func (s *cpuTopology) SetUncore(uncore Uncore) error {
@@ -148,6 +272,14 @@ func (s *cpuTopology) getEffectiveUncore() Uncore {
 	}
 	return s.uncore
 }
+
+func (s *cpuTopology) uncoreSource() UncoreLevel {
+	if s.uncore == nil {
+		return UncoreLevelDefault
+	}
+	return UncoreLevelTopology
+}
+
 func (s *cpuTopology) applyUncore() error {
 	for _, pkg := range s.packages {
 		if err := pkg.applyUncore(); err != nil {
@@ -167,6 +299,15 @@ func (c *cpuPackage) applyUncore() error {
 			return err
 		}
 	}
+	if len(c.domains) == 0 {
+		return nil
+	}
+	uncore := c.getEffectiveUncore()
+	for _, domain := range c.domains {
+		if err := domain.applyInheritedUncore(uncore); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -177,25 +318,268 @@ func (c *cpuPackage) getEffectiveUncore() Uncore {
 	return c.topology.getEffectiveUncore()
 }
 
+func (c *cpuPackage) uncoreSource() UncoreLevel {
+	if c.uncore != nil {
+		return UncoreLevelPackage
+	}
+	return c.topology.uncoreSource()
+}
+
 func (d *cpuDie) SetUncore(uncore Uncore) error {
+	if uncore != nil && len(d.domains) == 0 {
+		return noUncoreDomainsError(d.parentSocket.getID(), d.id)
+	}
 	d.uncore = uncore
 	return d.applyUncore()
 }
 
 func (d *cpuDie) applyUncore() error {
-	return d.getEffectiveUncore().write(d.parentSocket.getID(), d.id)
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.applyUncore_unsafe()
+}
+
+func (d *cpuDie) applyUncore_unsafe() error {
+	uncore := d.getEffectiveUncore_unsafe()
+	for _, domain := range d.domains {
+		if err := domain.applyInheritedUncore(uncore); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
+// die uncore takes precedence over uncore requested by pools with cpus on the die,
+// which in turn takes precedence over the package uncore
 func (d *cpuDie) getEffectiveUncore() Uncore {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.getEffectiveUncore_unsafe()
+}
+
+func (d *cpuDie) getEffectiveUncore_unsafe() Uncore {
 	if d.uncore != nil {
 		return d.uncore
 	}
+	if len(d.poolUncores) > 0 {
+		requests := make([]Uncore, 0, len(d.poolUncores))
+		for _, uncore := range d.poolUncores {
+			requests = append(requests, uncore)
+		}
+		return arbitrateUncore(requests)
+	}
 	return d.parentSocket.getEffectiveUncore()
 }
 
-func readUncoreProperty(pkgID, dieID uint, property string) (uint, error) {
-	fullPath := path.Join(basePath, fmt.Sprintf(uncorePathFmt, pkgID, dieID), property)
-	return readUintFromFile(fullPath)
+func (d *cpuDie) uncoreSource() UncoreLevel {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.uncore != nil {
+		return UncoreLevelDie
+	}
+	if len(d.poolUncores) > 0 {
+		return UncoreLevelPool
+	}
+	return d.parentSocket.uncoreSource()
+}
+
+// setPoolUncore records uncore requested by a pool with cpus on this die, nil uncore removes the request
+// die uncore is only rewritten if the request changed
+func (d *cpuDie) setPoolUncore(pool Pool, uncore Uncore) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.poolUncores[pool] == uncore {
+		return nil
+	}
+	if uncore != nil && len(d.domains) == 0 {
+		return noUncoreDomainsError(d.parentSocket.getID(), d.id)
+	}
+	if uncore == nil {
+		delete(d.poolUncores, pool)
+	} else {
+		if d.poolUncores == nil {
+			d.poolUncores = map[Pool]Uncore{}
+		}
+		d.poolUncores[pool] = uncore
+	}
+	return d.applyUncore_unsafe()
+}
+
+// uncore requested on a die without domains would not be written anywhere, TPMI domains of multi die packages
+// belong to the package
+func noUncoreDomainsError(pkgID uint, dieID uint) error {
+	return &FeatureError{
+		Feature: UncoreFeature,
+		Name:    "Uncore frequency",
+		Err:     fmt.Errorf("die %d of package %d has no uncore domains, uncore can only be set on its package", dieID, pkgID),
+	}
+}
+
+// arbitrateUncore combines uncore requests of multiple pools according to uncorePoolPolicy
+func arbitrateUncore(requests []Uncore) Uncore {
+	if len(requests) == 1 {
+		return requests[0]
+	}
+	min, max := requests[0].limits()
+	for _, request := range requests[1:] {
+		reqMin, reqMax := request.limits()
+		switch uncorePoolPolicy {
+		case UncorePoolPolicyMin:
+			min = minUint(min, reqMin)
+			max = minUint(max, reqMax)
+		default:
+			min = maxUint(min, reqMin)
+			max = maxUint(max, reqMax)
+		}
+	}
+	if min > max {
+		min = max
+	}
+	elc := arbitrateUncoreElc(requests)
+	// limits of requests without ELC can move the merged range past the merged floor
+	if elc != nil {
+		if elc.FloorFreq < min {
+			elc.FloorFreq = min
+		} else if elc.FloorFreq > max {
+			elc.FloorFreq = max
+		}
+	}
+	return &uncoreFreq{min: min, max: max, elc: elc}
+}
+
+// arbitrateUncoreElc combines ELC settings field by field, favouring performance with UncorePoolPolicyMax
+// and power saving with UncorePoolPolicyMin. requests without ELC settings are ignored
+func arbitrateUncoreElc(requests []Uncore) *UncoreElc {
+	var result *UncoreElc
+	for _, request := range requests {
+		elc := request.elcParams()
+		if elc == nil {
+			continue
+		}
+		if result == nil {
+			merged := *elc
+			result = &merged
+			continue
+		}
+		switch uncorePoolPolicy {
+		case UncorePoolPolicyMin:
+			result.FloorFreq = minUint(result.FloorFreq, elc.FloorFreq)
+			result.LowThresholdPercent = maxUint(result.LowThresholdPercent, elc.LowThresholdPercent)
+			result.HighThresholdPercent = maxUint(result.HighThresholdPercent, elc.HighThresholdPercent)
+			result.HighThresholdEnable = result.HighThresholdEnable && elc.HighThresholdEnable
+		default:
+			result.FloorFreq = maxUint(result.FloorFreq, elc.FloorFreq)
+			result.LowThresholdPercent = minUint(result.LowThresholdPercent, elc.LowThresholdPercent)
+			result.HighThresholdPercent = minUint(result.HighThresholdPercent, elc.HighThresholdPercent)
+			result.HighThresholdEnable = result.HighThresholdEnable || elc.HighThresholdEnable
+		}
+	}
+	return result
+}
+
+func minUint(a, b uint) uint {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxUint(a, b uint) uint {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func (pool *poolImpl) SetUncore(uncore Uncore) error {
+	return pool.SetUncoreContext(context.Background(), uncore)
+}
+
+// SetUncoreContext checks ctx between dies, previous uncore of the pool is restored if interrupted
+func (pool *poolImpl) SetUncoreContext(ctx context.Context, uncore Uncore) error {
+	if !IsFeatureSupported(UncoreFeature) {
+		return featureList.getFeatureIdError(UncoreFeature)
+	}
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	if uncore != nil {
+		if err := pool.checkUncoreDomains(); err != nil {
+			return err
+		}
+	}
+	previous := pool.uncore
+	pool.uncore = uncore
+	err := pool.reconcileUncoreContext(ctx)
+	// completed, or failed for a reason other than ctx being done
+	if err == nil || ctx.Err() == nil || !errors.Is(err, ctx.Err()) {
+		return err
+	}
+	pool.uncore = previous
+	rollbackErr := pool.reconcileUncore()
+	return &ProgressError{
+		Op:         "set uncore",
+		Applied:    CpuList{},
+		Pending:    append(CpuList{}, pool.cpus...),
+		RolledBack: rollbackErr == nil,
+		Err:        errors.Join(err, rollbackErr),
+	}
+}
+
+func (pool *poolImpl) GetUncore() Uncore {
+	return pool.uncore
+}
+
+// reconcileUncore places uncore request of the pool on all dies with cpus in the pool,
+// and removes it from dies that no longer have any cpu of the pool
+func (pool *poolImpl) reconcileUncore() error {
+	return pool.reconcileUncoreContext(context.Background())
+}
+
+func (pool *poolImpl) reconcileUncoreContext(ctx context.Context) error {
+	for _, pkg := range *pool.host.Topology().Packages() {
+		for _, die := range *pkg.Dies() {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			var uncore Uncore
+			if pool.uncore != nil && pool.hasCpuOn(die) {
+				uncore = pool.uncore
+			}
+			if err := die.setPoolUncore(pool, uncore); err != nil {
+				return fmt.Errorf("failed to apply uncore of pool %s: %w", pool.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// fails if a die with cpus of the pool has no uncore domain to apply pool uncore to
+func (pool *poolImpl) checkUncoreDomains() error {
+	for _, pkg := range *pool.host.Topology().Packages() {
+		for _, die := range *pkg.Dies() {
+			if pool.hasCpuOn(die) && len(die.UncoreDomains()) == 0 {
+				return noUncoreDomainsError(pkg.getID(), die.getID())
+			}
+		}
+	}
+	return nil
+}
+
+func (pool *poolImpl) hasCpuOn(die Die) bool {
+	for _, cpu := range *die.CPUs() {
+		if pool.cpus.Contains(cpu) {
+			return true
+		}
+	}
+	return false
+}
+
+func (reservedPool *reservedPoolType) SetUncore(Uncore) error {
+	return fmt.Errorf("cannot set uncore for reserved pool")
+}
+
+func (reservedPool *reservedPoolType) SetUncoreContext(context.Context, Uncore) error {
+	return reservedPool.SetUncore(nil)
 }
 
 func normalizeUncoreFreq(freq uint) uint {
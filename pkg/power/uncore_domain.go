@@ -0,0 +1,385 @@
+package power
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// intel_uncore_frequency exposes uncore controls either in the legacy layout with one package_XX_die_YY directory per
+// die, or with TPMI (Granite Rapids, Sierra Forest and newer) in uncoreNN directories, one per power domain and fabric
+// cluster. TPMI domain IDs do not map to die_id of the cpu topology
+const (
+	uncoreDomainPkgIdFile     = "package_id"
+	uncoreDomainIdFile        = "domain_id"
+	uncoreDomainClusterIdFile = "fabric_cluster_id"
+)
+
+var (
+	uncoreLegacyDirRegex = regexp.MustCompile(`^package_(\d+)_die_(\d+)$`)
+	uncoreTpmiDirRegex   = regexp.MustCompile(`^uncore(\d+)$`)
+)
+
+// uncoreDomainInfo stores properties of uncore domain directory discovered during library initialisation
+type uncoreDomainInfo struct {
+	// path relative to basePath
+	dir       string
+	pkgID     uint
+	domainID  uint
+	clusterID uint
+	tpmi      bool
+	initMin   uint
+	initMax   uint
+	// ELC settings at initialisation, nil if the driver does not expose ELC
+	initElc *UncoreElc
+}
+
+// populated during library initialisation, attached to the topology when it is discovered
+var uncoreDomainInfos []uncoreDomainInfo
+
+// discoverUncoreDomains enumerates uncore domains, TPMI layout is used if present as legacy
+// directories, if also exposed, control the same hardware
+func discoverUncoreDomains() ([]uncoreDomainInfo, error) {
+	entries, err := os.ReadDir(path.Join(basePath, uncoreDirName))
+	if err != nil {
+		return nil, err
+	}
+	var legacy, tpmi []uncoreDomainInfo
+	for _, entry := range entries {
+		dir := path.Join(uncoreDirName, entry.Name())
+		if match := uncoreLegacyDirRegex.FindStringSubmatch(entry.Name()); match != nil {
+			pkgID, _ := strconv.Atoi(match[1])
+			dieID, _ := strconv.Atoi(match[2])
+			legacy = append(legacy, uncoreDomainInfo{dir: dir, pkgID: uint(pkgID), domainID: uint(dieID)})
+		} else if uncoreTpmiDirRegex.MatchString(entry.Name()) {
+			info := uncoreDomainInfo{dir: dir, tpmi: true}
+			if info.pkgID, err = readUncoreDomainProperty(dir, uncoreDomainPkgIdFile); err != nil {
+				return nil, fmt.Errorf("failed to read uncore domain package: %w", err)
+			}
+			if info.domainID, err = readUncoreDomainProperty(dir, uncoreDomainIdFile); err != nil {
+				return nil, fmt.Errorf("failed to read uncore domain id: %w", err)
+			}
+			if info.clusterID, err = readUncoreDomainProperty(dir, uncoreDomainClusterIdFile); err != nil {
+				return nil, fmt.Errorf("failed to read uncore domain fabric cluster: %w", err)
+			}
+			tpmi = append(tpmi, info)
+		}
+	}
+	domains := legacy
+	if len(tpmi) > 0 {
+		domains = tpmi
+	}
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("no uncore domains found")
+	}
+	sort.Slice(domains, func(i, j int) bool { return domains[i].less(&domains[j]) })
+	for i := range domains {
+		if domains[i].initMax, err = readUncoreDomainProperty(domains[i].dir, uncoreInitMaxFreqFile); err != nil {
+			return nil, fmt.Errorf("failed to determine init freq: %w", err)
+		}
+		if domains[i].initMin, err = readUncoreDomainProperty(domains[i].dir, uncoreInitMinFreqFile); err != nil {
+			return nil, fmt.Errorf("failed to determine init freq: %w", err)
+		}
+		if domains[i].initElc, err = readUncoreDomainElc(domains[i].dir); err != nil {
+			return nil, fmt.Errorf("failed to determine initial ELC settings: %w", err)
+		}
+	}
+	return domains, nil
+}
+
+func (i *uncoreDomainInfo) less(other *uncoreDomainInfo) bool {
+	if i.pkgID != other.pkgID {
+		return i.pkgID < other.pkgID
+	}
+	if i.domainID != other.domainID {
+		return i.domainID < other.domainID
+	}
+	return i.clusterID < other.clusterID
+}
+
+// reads ELC settings of a domain, nil if the driver does not expose ELC
+func readUncoreDomainElc(dir string) (*UncoreElc, error) {
+	if _, err := os.Stat(path.Join(basePath, dir, uncoreElcFloorFreqFile)); err != nil {
+		return nil, nil
+	}
+	elc := &UncoreElc{}
+	var err error
+	if elc.FloorFreq, err = readUncoreDomainProperty(dir, uncoreElcFloorFreqFile); err != nil {
+		return nil, err
+	}
+	if elc.LowThresholdPercent, err = readUncoreDomainProperty(dir, uncoreElcLowThresholdFile); err != nil {
+		return nil, err
+	}
+	if elc.HighThresholdPercent, err = readUncoreDomainProperty(dir, uncoreElcHighThresholdFile); err != nil {
+		return nil, err
+	}
+	enable, err := readUncoreDomainProperty(dir, uncoreElcHighEnableFile)
+	if err != nil {
+		return nil, err
+	}
+	elc.HighThresholdEnable = enable == 1
+	return elc, nil
+}
+
+func readUncoreDomainProperty(dir string, property string) (uint, error) {
+	return readUintFromFile(path.Join(basePath, dir, property))
+}
+
+type (
+	uncoreDomain struct {
+		uncoreDomainInfo
+		// die or package the domain was attached to
+		parent hasUncore
+		uncore Uncore
+	}
+	// UncoreDomain represents a single uncore frequency control exposed by the driver
+	UncoreDomain interface {
+		hasUncore
+		// die id in the legacy layout, power domain id with TPMI
+		ID() uint
+		PackageID() uint
+		// always 0 in the legacy layout
+		FabricClusterID() uint
+		applyInheritedUncore(inherited Uncore) error
+		ReadUncore() (UncoreInfo, error)
+	}
+	hasUncoreDomains interface {
+		UncoreDomains() []UncoreDomain
+		// ReadUncore reads back the uncore state of all domains, ordered by package, domain and cluster id
+		ReadUncore() ([]UncoreInfo, error)
+	}
+)
+
+// UncoreLevel is the level of the hierarchy an uncore setting was requested at
+type UncoreLevel uint
+
+const (
+	// nothing requested, initial limits of the domain are in effect
+	UncoreLevelDefault UncoreLevel = iota
+	UncoreLevelTopology
+	UncoreLevelPackage
+	UncoreLevelPool
+	UncoreLevelDie
+	UncoreLevelDomain
+)
+
+func (l UncoreLevel) String() string {
+	switch l {
+	case UncoreLevelDefault:
+		return "default"
+	case UncoreLevelTopology:
+		return "topology"
+	case UncoreLevelPackage:
+		return "package"
+	case UncoreLevelPool:
+		return "pool"
+	case UncoreLevelDie:
+		return "die"
+	case UncoreLevelDomain:
+		return "domain"
+	}
+	return fmt.Sprintf("UncoreLevel(%d)", uint(l))
+}
+
+// UncoreInfo is the uncore state of a single domain as read from the driver, frequencies in kHz
+type UncoreInfo struct {
+	PackageID       uint
+	DomainID        uint
+	FabricClusterID uint
+	Min             uint
+	Max             uint
+	InitialMin      uint
+	InitialMax      uint
+	// 0 if the driver does not report current frequency
+	Current uint
+	// level the effective setting came from
+	Source UncoreLevel
+}
+
+func (d *uncoreDomain) ID() uint {
+	return d.domainID
+}
+
+func (d *uncoreDomain) PackageID() uint {
+	return d.pkgID
+}
+
+func (d *uncoreDomain) FabricClusterID() uint {
+	return d.clusterID
+}
+
+// SetUncore sets uncore on a single domain, taking precedence over all other levels
+func (d *uncoreDomain) SetUncore(uncore Uncore) error {
+	d.uncore = uncore
+	return d.applyUncore()
+}
+
+func (d *uncoreDomain) applyUncore() error {
+	return d.applyInheritedUncore(d.parent.getEffectiveUncore())
+}
+
+// applyInheritedUncore writes the domain uncore or, if not set, the one inherited from the parent.
+// if nothing was set anywhere in the hierarchy the initial limits of the domain are restored, initial ELC settings
+// are restored whenever the uncore in effect has none so settings of a removed ELC request do not persist
+func (d *uncoreDomain) applyInheritedUncore(inherited Uncore) error {
+	uncore := d.uncore
+	if uncore == nil {
+		uncore = inherited
+	}
+	if uncore == defaultUncore {
+		uncore = &uncoreFreq{min: d.initMin, max: d.initMax}
+	}
+	if d.initElc != nil && uncore.elcParams() == nil {
+		min, max := uncore.limits()
+		elc := *d.initElc
+		// floor has to stay within limits requested without ELC
+		if elc.FloorFreq < min {
+			elc.FloorFreq = min
+		} else if elc.FloorFreq > max {
+			elc.FloorFreq = max
+		}
+		uncore = &uncoreFreq{min: min, max: max, elc: &elc}
+	}
+	return uncore.write(d.dir)
+}
+
+func (d *uncoreDomain) uncoreSource() UncoreLevel {
+	if d.uncore != nil {
+		return UncoreLevelDomain
+	}
+	return d.parent.uncoreSource()
+}
+
+// ReadUncore reads currently applied limits and current frequency of the domain
+func (d *uncoreDomain) ReadUncore() (UncoreInfo, error) {
+	info := UncoreInfo{
+		PackageID:       d.pkgID,
+		DomainID:        d.domainID,
+		FabricClusterID: d.clusterID,
+		InitialMin:      d.initMin,
+		InitialMax:      d.initMax,
+		Source:          d.uncoreSource(),
+	}
+	var err error
+	if info.Min, err = readUncoreDomainProperty(d.dir, uncoreMinFreqFile); err != nil {
+		return info, fmt.Errorf("failed to read uncore min frequency of %s: %w", d.dir, err)
+	}
+	if info.Max, err = readUncoreDomainProperty(d.dir, uncoreMaxFreqFile); err != nil {
+		return info, fmt.Errorf("failed to read uncore max frequency of %s: %w", d.dir, err)
+	}
+	info.Current, err = readUncoreDomainProperty(d.dir, uncoreCurrentFreqFile)
+	if err != nil && !os.IsNotExist(err) {
+		return info, fmt.Errorf("failed to read uncore current frequency of %s: %w", d.dir, err)
+	}
+	return info, nil
+}
+
+func (d *uncoreDomain) getEffectiveUncore() Uncore {
+	if d.uncore != nil {
+		return d.uncore
+	}
+	return d.parent.getEffectiveUncore()
+}
+
+// attaches discovered uncore domains to packages and dies of the topology
+func (s *cpuTopology) addUncoreDomains(infos []uncoreDomainInfo) {
+	for _, info := range infos {
+		pkg, exists := s.packages[info.pkgID]
+		if !exists {
+			log.Info("uncore domain of unknown package ignored", "dir", info.dir)
+			continue
+		}
+		pkg.addUncoreDomain(info)
+	}
+}
+
+func (s *cpuTopology) ReadUncore() ([]UncoreInfo, error) {
+	return readUncoreInfos(s.UncoreDomains())
+}
+
+func (s *cpuTopology) UncoreDomains() []UncoreDomain {
+	domains := make([]UncoreDomain, 0)
+	for _, pkg := range *s.Packages() {
+		domains = append(domains, pkg.UncoreDomains()...)
+	}
+	sortUncoreDomains(domains)
+	return domains
+}
+
+// legacy domains are attached to their die. TPMI domains are attached to the die if the package has a single die,
+// otherwise to the package so they are only controlled by package and topology uncore, as the driver does not
+// report which dies a domain covers
+func (c *cpuPackage) addUncoreDomain(info uncoreDomainInfo) {
+	var die Die
+	if !info.tpmi {
+		die = c.dies[info.domainID]
+	} else if len(c.dies) == 1 {
+		for _, d := range c.dies {
+			die = d
+		}
+	}
+	if die != nil {
+		die.addUncoreDomain(&uncoreDomain{uncoreDomainInfo: info, parent: die})
+		return
+	}
+	if !info.tpmi {
+		log.Info("uncore domain of unknown die ignored", "dir", info.dir)
+		return
+	}
+	c.domains = append(c.domains, &uncoreDomain{uncoreDomainInfo: info, parent: c})
+}
+
+func (c *cpuPackage) ReadUncore() ([]UncoreInfo, error) {
+	return readUncoreInfos(c.UncoreDomains())
+}
+
+func (c *cpuPackage) UncoreDomains() []UncoreDomain {
+	domains := make([]UncoreDomain, len(c.domains))
+	copy(domains, c.domains)
+	for _, die := range c.dies {
+		domains = append(domains, die.UncoreDomains()...)
+	}
+	sortUncoreDomains(domains)
+	return domains
+}
+
+func (d *cpuDie) addUncoreDomain(domain UncoreDomain) {
+	d.domains = append(d.domains, domain)
+}
+
+func (d *cpuDie) ReadUncore() ([]UncoreInfo, error) {
+	return readUncoreInfos(d.UncoreDomains())
+}
+
+func (d *cpuDie) UncoreDomains() []UncoreDomain {
+	domains := make([]UncoreDomain, len(d.domains))
+	copy(domains, d.domains)
+	return domains
+}
+
+func sortUncoreDomains(domains []UncoreDomain) {
+	sort.Slice(domains, func(i, j int) bool {
+		if domains[i].PackageID() != domains[j].PackageID() {
+			return domains[i].PackageID() < domains[j].PackageID()
+		}
+		if domains[i].ID() != domains[j].ID() {
+			return domains[i].ID() < domains[j].ID()
+		}
+		return domains[i].FabricClusterID() < domains[j].FabricClusterID()
+	})
+}
+
+func readUncoreInfos(domains []UncoreDomain) ([]UncoreInfo, error) {
+	infos := make([]UncoreInfo, len(domains))
+	for i, domain := range domains {
+		info, err := domain.ReadUncore()
+		if err != nil {
+			return nil, err
+		}
+		infos[i] = info
+	}
+	return infos, nil
+}
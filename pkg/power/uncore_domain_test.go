@@ -0,0 +1,274 @@
+package power
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tpmiDomainFiles(pkg, domain, cluster uint) map[string]string {
+	return map[string]string{
+		"initMax":                 "2400000",
+		"initMin":                 fmt.Sprint(800000 + domain*100000),
+		"Max":                     "2400000",
+		"Min":                     "800000",
+		uncoreDomainPkgIdFile:     fmt.Sprint(pkg),
+		uncoreDomainIdFile:        fmt.Sprint(domain),
+		uncoreDomainClusterIdFile: fmt.Sprint(cluster),
+	}
+}
+
+func TestDiscoverUncoreDomains(t *testing.T) {
+	// legacy
+	teardown := setupUncoreTests(map[string]map[string]string{
+		"package_01_die_00": {"initMax": "2400000", "initMin": "1000000"},
+		"package_00_die_01": {"initMax": "2200000", "initMin": "1100000"},
+		"package_00_die_00": {"initMax": "2000000", "initMin": "1200000"},
+	}, "")
+	domains, err := discoverUncoreDomains()
+	assert.NoError(t, err)
+	assert.Equal(t, []uncoreDomainInfo{
+		{dir: uncoreDirName + "/package_00_die_00", pkgID: 0, domainID: 0, initMin: 1200000, initMax: 2000000},
+		{dir: uncoreDirName + "/package_00_die_01", pkgID: 0, domainID: 1, initMin: 1100000, initMax: 2200000},
+		{dir: uncoreDirName + "/package_01_die_00", pkgID: 1, domainID: 0, initMin: 1000000, initMax: 2400000},
+	}, domains)
+	teardown()
+
+	// tpmi takes precedence over legacy directories
+	teardown = setupUncoreTests(map[string]map[string]string{
+		"package_00_die_00": {"initMax": "2000000", "initMin": "1200000"},
+		"uncore00":          tpmiDomainFiles(0, 0, 0),
+		"uncore01":          tpmiDomainFiles(0, 1, 0),
+		"uncore02":          tpmiDomainFiles(0, 3, 0),
+		"uncore03":          tpmiDomainFiles(1, 0, 0),
+	}, "")
+	domains, err = discoverUncoreDomains()
+	assert.NoError(t, err)
+	assert.Len(t, domains, 4)
+	assert.Equal(t, uncoreDomainInfo{
+		dir: uncoreDirName + "/uncore02", pkgID: 0, domainID: 3, tpmi: true, initMin: 1100000, initMax: 2400000,
+	}, domains[2])
+	teardown()
+
+	// missing domain id
+	files := tpmiDomainFiles(0, 0, 0)
+	delete(files, uncoreDomainIdFile)
+	teardown = setupUncoreTests(map[string]map[string]string{"uncore00": files}, "")
+	_, err = discoverUncoreDomains()
+	assert.ErrorContains(t, err, "failed to read uncore domain id")
+	teardown()
+
+	// nothing recognised
+	teardown = setupUncoreTests(map[string]map[string]string{"something": {}}, "")
+	_, err = discoverUncoreDomains()
+	assert.ErrorContains(t, err, "no uncore domains found")
+	teardown()
+}
+
+func Test_initUncoreTpmi(t *testing.T) {
+	files := tpmiDomainFiles(0, 1, 0)
+	files["initMax"] = "2200000"
+	defer setupUncoreTests(map[string]map[string]string{
+		"uncore00": tpmiDomainFiles(0, 0, 0),
+		"uncore01": files,
+	}, uncoreKmodName+" 324 0 - Live 0000ffff3ea334\n")()
+
+	feature := initUncore()
+	assert.NoError(t, feature.err)
+	assert.Equal(t, "TPMI", feature.driver)
+	assert.Len(t, uncoreDomainInfos, 2)
+	// limits accepted by all domains
+	assert.Equal(t, uint(900000), defaultUncore.min)
+	assert.Equal(t, uint(2200000), defaultUncore.max)
+	assert.False(t, uncoreElcSupported)
+
+	// within limits of the first domain only
+	_, err := NewUncore(800000, 2200000)
+	assert.ErrorIs(t, err, ErrOutOfRange)
+	_, err = NewUncore(900000, 2400000)
+	assert.ErrorIs(t, err, ErrOutOfRange)
+	_, err = NewUncore(900000, 2200000)
+	assert.NoError(t, err)
+}
+
+func TestCpuTopology_addUncoreDomains(t *testing.T) {
+	// package 0 has one die, package 1 has two
+	pkg0 := &cpuPackage{id: 0, dies: dieList{0: &cpuDie{id: 0}}}
+	pkg1 := &cpuPackage{id: 1, dies: dieList{0: &cpuDie{id: 0}, 1: &cpuDie{id: 1}}}
+	pkg0.dies[0].(*cpuDie).parentSocket = pkg0
+	topo := &cpuTopology{packages: packageList{0: pkg0, 1: pkg1}}
+	pkg0.topology = topo
+	pkg1.topology = topo
+
+	topo.addUncoreDomains([]uncoreDomainInfo{
+		{dir: "legacy", pkgID: 1, domainID: 1},
+		{dir: "unknown die", pkgID: 1, domainID: 7},
+		{dir: "unknown pkg", pkgID: 5, domainID: 0},
+		{dir: "tpmi0", pkgID: 0, domainID: 0, tpmi: true},
+		{dir: "tpmi3", pkgID: 0, domainID: 3, tpmi: true},
+		{dir: "tpmi1", pkgID: 1, domainID: 1, tpmi: true},
+		{dir: "tpmi-io", pkgID: 1, domainID: 4, tpmi: true},
+	})
+
+	// single die takes all tpmi domains of the package
+	assert.Len(t, pkg0.dies[0].UncoreDomains(), 2)
+	assert.Empty(t, pkg0.domains)
+	// legacy domain on matching die, tpmi domains of a package with multiple dies on package
+	// even if the domain id matches a die id
+	assert.Len(t, pkg1.dies[1].UncoreDomains(), 1)
+	assert.Equal(t, "legacy", pkg1.dies[1].UncoreDomains()[0].(*uncoreDomain).dir)
+	assert.Empty(t, pkg1.dies[0].UncoreDomains())
+	assert.Len(t, pkg1.domains, 2)
+	for _, domain := range pkg1.domains {
+		assert.Equal(t, pkg1, domain.(*uncoreDomain).parent)
+	}
+
+	domains := topo.UncoreDomains()
+	assert.Len(t, domains, 5)
+	for i, expected := range []struct{ pkg, id uint }{{0, 0}, {0, 3}, {1, 1}, {1, 1}, {1, 4}} {
+		assert.Equal(t, expected.pkg, domains[i].PackageID())
+		assert.Equal(t, expected.id, domains[i].ID())
+	}
+	assert.Len(t, pkg1.UncoreDomains(), 3)
+}
+
+func TestUncoreDomain_SetUncore(t *testing.T) {
+	defer setupUncoreTests(map[string]map[string]string{
+		"uncore00": tpmiDomainFiles(0, 0, 0),
+		"uncore01": tpmiDomainFiles(0, 1, 0),
+	}, "")()
+	domains, err := discoverUncoreDomains()
+	assert.NoError(t, err)
+
+	pkg := &cpuPackage{id: 0, dies: dieList{}}
+	topo := &cpuTopology{packages: packageList{0: pkg}}
+	pkg.topology = topo
+	topo.addUncoreDomains(domains)
+	assert.Len(t, pkg.domains, 2)
+	domain0 := pkg.domains[0]
+	domain1 := pkg.domains[1]
+
+	// package level applies to package domains
+	assert.NoError(t, topo.SetUncore(&uncoreFreq{min: 1_500_000, max: 2_000_000}))
+	value, _ := readUncoreDomainProperty(uncoreDirName+"/uncore01", uncoreMaxFreqFile)
+	assert.Equal(t, uint(2_000_000), value)
+
+	// domain level takes precedence
+	domainUncore := &uncoreFreq{min: 1_600_000, max: 1_800_000}
+	assert.NoError(t, domain1.SetUncore(domainUncore))
+	assert.Equal(t, domainUncore, domain1.getEffectiveUncore())
+	assert.NoError(t, pkg.SetUncore(&uncoreFreq{min: 1_500_000, max: 2_200_000}))
+	value, _ = readUncoreDomainProperty(uncoreDirName+"/uncore01", uncoreMaxFreqFile)
+	assert.Equal(t, uint(1_800_000), value)
+	value, _ = readUncoreDomainProperty(uncoreDirName+"/uncore00", uncoreMaxFreqFile)
+	assert.Equal(t, uint(2_200_000), value)
+
+	// nothing set restores initial limits of each domain
+	pkg.uncore = nil
+	topo.uncore = nil
+	assert.NoError(t, domain0.applyUncore())
+	assert.NoError(t, domain1.SetUncore(nil))
+	value, _ = readUncoreDomainProperty(uncoreDirName+"/uncore00", uncoreMinFreqFile)
+	assert.Equal(t, uint(800_000), value)
+	value, _ = readUncoreDomainProperty(uncoreDirName+"/uncore01", uncoreMinFreqFile)
+	assert.Equal(t, uint(900_000), value)
+}
+
+func TestUncoreDomain_restoresInitialElc(t *testing.T) {
+	defer setupUncoreTests(map[string]map[string]string{
+		"uncore00": elcDomainFiles(0, 0, 0),
+	}, "")()
+	domains, err := discoverUncoreDomains()
+	assert.NoError(t, err)
+
+	pkg := &cpuPackage{id: 0, dies: dieList{}}
+	topo := &cpuTopology{packages: packageList{0: pkg}}
+	pkg.topology = topo
+	topo.addUncoreDomains(domains)
+	dir := uncoreDirName + "/uncore00"
+
+	elc := &UncoreElc{LowThresholdPercent: 30, HighThresholdPercent: 60, FloorFreq: 1_500_000}
+	assert.NoError(t, pkg.SetUncore(&uncoreFreq{min: 1_200_000, max: 2_000_000, elc: elc}))
+	value, _ := readUncoreDomainProperty(dir, uncoreElcLowThresholdFile)
+	assert.Equal(t, uint(30), value)
+	value, _ = readUncoreDomainProperty(dir, uncoreElcHighEnableFile)
+	assert.Equal(t, uint(0), value)
+
+	// uncore without elc restores initial elc settings, floor within the requested limits
+	assert.NoError(t, pkg.SetUncore(&uncoreFreq{min: 1_200_000, max: 2_000_000}))
+	for file, expected := range map[string]uint{
+		uncoreElcLowThresholdFile:  10,
+		uncoreElcHighThresholdFile: 95,
+		uncoreElcHighEnableFile:    1,
+		uncoreElcFloorFreqFile:     1_200_000,
+	} {
+		value, _ = readUncoreDomainProperty(dir, file)
+		assert.Equal(t, expected, value, file)
+	}
+
+	// removing the request restores initial limits and elc
+	assert.NoError(t, pkg.SetUncore(&uncoreFreq{min: 1_200_000, max: 2_000_000, elc: elc}))
+	assert.NoError(t, pkg.SetUncore(nil))
+	value, _ = readUncoreDomainProperty(dir, uncoreElcFloorFreqFile)
+	assert.Equal(t, uint(1_000_000), value)
+	value, _ = readUncoreDomainProperty(dir, uncoreElcLowThresholdFile)
+	assert.Equal(t, uint(10), value)
+}
+
+func TestCpuTopology_ReadUncore(t *testing.T) {
+	defer setupUncoreTests(map[string]map[string]string{
+		"package_00_die_00": {"initMax": "2400000", "initMin": "800000", "Max": "2400000", "Min": "800000", uncoreCurrentFreqFile: "1500000"},
+		"package_00_die_01": {"initMax": "2400000", "initMin": "800000", "Max": "2400000", "Min": "800000"},
+	}, "")()
+	infos, err := discoverUncoreDomains()
+	assert.NoError(t, err)
+
+	pkg := &cpuPackage{id: 0, dies: dieList{}}
+	die0 := &cpuDie{id: 0, parentSocket: pkg}
+	die1 := &cpuDie{id: 1, parentSocket: pkg}
+	pkg.dies[0] = die0
+	pkg.dies[1] = die1
+	topo := &cpuTopology{packages: packageList{0: pkg}}
+	pkg.topology = topo
+	topo.addUncoreDomains(infos)
+
+	values, err := topo.ReadUncore()
+	assert.NoError(t, err)
+	assert.Equal(t, []UncoreInfo{
+		{PackageID: 0, DomainID: 0, Min: 800000, Max: 2400000, InitialMin: 800000, InitialMax: 2400000, Current: 1500000, Source: UncoreLevelDefault},
+		{PackageID: 0, DomainID: 1, Min: 800000, Max: 2400000, InitialMin: 800000, InitialMax: 2400000, Source: UncoreLevelDefault},
+	}, values)
+
+	assert.NoError(t, topo.SetUncore(&uncoreFreq{min: 1_000_000, max: 2_000_000}))
+	assert.NoError(t, die1.setPoolUncore(new(poolMock), &uncoreFreq{min: 1_200_000, max: 2_200_000}))
+	values, err = pkg.ReadUncore()
+	assert.NoError(t, err)
+	assert.Equal(t, UncoreLevelTopology, values[0].Source)
+	assert.Equal(t, uint(1_000_000), values[0].Min)
+	assert.Equal(t, UncoreLevelPool, values[1].Source)
+	assert.Equal(t, uint(2_200_000), values[1].Max)
+
+	assert.NoError(t, pkg.SetUncore(&uncoreFreq{min: 1_000_000, max: 2_000_000}))
+	assert.NoError(t, die1.SetUncore(&uncoreFreq{min: 1_000_000, max: 2_000_000}))
+	values, err = die0.ReadUncore()
+	assert.NoError(t, err)
+	assert.Len(t, values, 1)
+	assert.Equal(t, UncoreLevelPackage, values[0].Source)
+	values, err = die1.ReadUncore()
+	assert.NoError(t, err)
+	assert.Equal(t, UncoreLevelDie, values[0].Source)
+
+	assert.NoError(t, die1.UncoreDomains()[0].SetUncore(&uncoreFreq{min: 1_000_000, max: 2_000_000}))
+	info, err := die1.UncoreDomains()[0].ReadUncore()
+	assert.NoError(t, err)
+	assert.Equal(t, UncoreLevelDomain, info.Source)
+	assert.Equal(t, "domain", info.Source.String())
+
+	// missing limits
+	assert.NoError(t, os.Remove(filepath.Join(basePath, uncoreDirName, "package_00_die_00", uncoreMaxFreqFile)))
+	_, err = topo.ReadUncore()
+	assert.ErrorContains(t, err, "failed to read uncore max frequency")
+}
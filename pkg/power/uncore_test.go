@@ -6,6 +6,7 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -16,13 +17,28 @@ type mockUncore struct {
 	mock.Mock
 }
 
-func (m *mockUncore) write(pkIgD, dieID uint) error {
-	return m.Called(pkIgD, dieID).Error(0)
+func (m *mockUncore) write(dir string) error {
+	return m.Called(dir).Error(0)
+}
+
+func (m *mockUncore) elcParams() *UncoreElc {
+	ret := m.Called().Get(0)
+	if ret == nil {
+		return nil
+	}
+	return ret.(*UncoreElc)
+}
+
+func (m *mockUncore) limits() (uint, uint) {
+	args := m.Called()
+	return args.Get(0).(uint), args.Get(1).(uint)
 }
 
 func setupUncoreTests(files map[string]map[string]string, modulesFileContent string) func() {
 	origBasePath := basePath
 	basePath = "testing/cpus"
+	// fake files are recreated, cached values of previous tests do not apply
+	sysfsWrites.reset(false)
 
 	origModulesFile := kernelModulesFilePath
 	kernelModulesFilePath = basePath + "/kernelModules"
@@ -62,6 +78,10 @@ func setupUncoreTests(files map[string]map[string]string, modulesFileContent str
 				if err := os.WriteFile(path.Join(pkgUncoreDir, uncoreMinFreqFile), []byte(value), 0644); err != nil {
 					panic(err)
 				}
+			default:
+				if err := os.WriteFile(path.Join(pkgUncoreDir, file), []byte(value), 0644); err != nil {
+					panic(err)
+				}
 			}
 		}
 	}
@@ -74,8 +94,15 @@ func setupUncoreTests(files map[string]map[string]string, modulesFileContent str
 		basePath = origBasePath
 
 		defaultUncore = &uncoreFreq{}
+		uncoreDomainInfos = nil
+		uncoreElcSupported = false
 	}
 }
+
+// reads uncore property of a die in the legacy layout
+func readUncoreProperty(pkgID, dieID uint, property string) (uint, error) {
+	return readUncoreDomainProperty(fmt.Sprintf(uncoreDirName+"/package_%02d_die_%02d", pkgID, dieID), property)
+}
 func Test_initUncore(t *testing.T) {
 	var feature featureStatus
 	var teardown func()
@@ -150,12 +177,13 @@ func TestNewUncore(t *testing.T) {
 	// max too high
 	ucre, err = NewUncore(1_400_000, 9999999)
 	assert.Nil(t, ucre)
-	assert.ErrorContains(t, err, "Max frequency is higher than")
+	assert.ErrorContains(t, err, "uncore max frequency 9999999 is out of range")
+	assert.ErrorIs(t, err, ErrOutOfRange)
 
 	// min too low
 	ucre, err = NewUncore(100, 2_200_000)
 	assert.Nil(t, ucre)
-	assert.ErrorContains(t, err, "Min frequency is lower than")
+	assert.ErrorContains(t, err, "uncore min frequency 100 is out of range")
 
 	//uncore not supported
 	featureList[UncoreFeature].err = fmt.Errorf("uncore borked")
@@ -163,6 +191,67 @@ func TestNewUncore(t *testing.T) {
 	assert.ErrorIs(t, err, featureList[UncoreFeature].err)
 }
 
+func TestNewUncoreWithElc(t *testing.T) {
+	defer setupUncoreTests(map[string]map[string]string{}, "")()
+	defaultUncore.min = 1_200_000
+	defaultUncore.max = 2_400_000
+	elc := UncoreElc{LowThresholdPercent: 10, HighThresholdPercent: 90, HighThresholdEnable: true, FloorFreq: 1_600_000}
+
+	// not supported by the driver
+	ucre, err := NewUncoreWithElc(1_400_000, 2_200_000, elc)
+	assert.Nil(t, ucre)
+	assert.ErrorContains(t, err, "not supported")
+
+	uncoreElcSupported = true
+	ucre, err = NewUncoreWithElc(1_400_000, 2_200_000, elc)
+	assert.NoError(t, err)
+	assert.Equal(t, &elc, ucre.elcParams())
+
+	// floor is normalized
+	elc.FloorFreq = 1_650_000
+	ucre, err = NewUncoreWithElc(1_400_000, 2_200_000, elc)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1_600_000), ucre.elcParams().FloorFreq)
+
+	// floor outside of requested range
+	elc.FloorFreq = 1_300_000
+	_, err = NewUncoreWithElc(1_400_000, 2_200_000, elc)
+	assert.ErrorContains(t, err, "ELC floor frequency")
+
+	// thresholds
+	elc.FloorFreq = 1_600_000
+	elc.HighThresholdPercent = 101
+	_, err = NewUncoreWithElc(1_400_000, 2_200_000, elc)
+	assert.ErrorContains(t, err, "ELC high threshold 101 is out of range 0-100")
+	elc.LowThresholdPercent = 50
+	elc.HighThresholdPercent = 40
+	_, err = NewUncoreWithElc(1_400_000, 2_200_000, elc)
+	assert.ErrorContains(t, err, "low threshold cannot be higher")
+
+	// invalid min/max
+	_, err = NewUncoreWithElc(100, 2_200_000, elc)
+	assert.ErrorContains(t, err, "uncore min frequency 100 is out of range")
+}
+
+func elcDomainFiles(pkg, domain, cluster uint) map[string]string {
+	files := tpmiDomainFiles(pkg, domain, cluster)
+	files[uncoreElcFloorFreqFile] = "1000000"
+	files[uncoreElcLowThresholdFile] = "10"
+	files[uncoreElcHighThresholdFile] = "95"
+	files[uncoreElcHighEnableFile] = "1"
+	return files
+}
+
+func Test_initUncoreElc(t *testing.T) {
+	defer setupUncoreTests(map[string]map[string]string{
+		"uncore00": elcDomainFiles(0, 0, 0),
+	}, uncoreKmodName+" 324 0 - Live 0000ffff3ea334\n")()
+
+	assert.NoError(t, initUncore().err)
+	assert.True(t, uncoreElcSupported)
+	assert.Equal(t, &UncoreElc{LowThresholdPercent: 10, HighThresholdPercent: 95, HighThresholdEnable: true, FloorFreq: 1_000_000}, uncoreDomainInfos[0].initElc)
+}
+
 func TestUncoreFreq_write(t *testing.T) {
 	defer setupUncoreTests(map[string]map[string]string{
 		"package_00_die_00": {
@@ -176,7 +265,7 @@ func TestUncoreFreq_write(t *testing.T) {
 	}, "")()
 
 	uncore := uncoreFreq{min: 1, max: 9323}
-	err := uncore.write(1, 0)
+	err := uncore.write(uncoreDirName + "/package_01_die_00")
 	assert.NoError(t, err)
 
 	value, _ := readUncoreProperty(1, 0, uncoreMinFreqFile)
@@ -186,8 +275,40 @@ func TestUncoreFreq_write(t *testing.T) {
 	assert.Equal(t, uint(9323), value)
 
 	// write to non-existing file
-	err = uncore.write(2, 3)
+	err = uncore.write(uncoreDirName + "/package_02_die_03")
 	assert.ErrorContains(t, err, "no such file or directory")
+
+	// elc settings
+	uncore.elc = &UncoreElc{LowThresholdPercent: 10, HighThresholdPercent: 95, HighThresholdEnable: true, FloorFreq: 1_200_000}
+	assert.NoError(t, uncore.write(uncoreDirName+"/package_01_die_00"))
+	for file, expected := range map[string]uint{
+		uncoreElcLowThresholdFile:  10,
+		uncoreElcHighThresholdFile: 95,
+		uncoreElcHighEnableFile:    1,
+		uncoreElcFloorFreqFile:     1_200_000,
+	} {
+		value, err := readUncoreProperty(1, 0, file)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, value, file)
+	}
+
+	// files are written in a fixed order
+	var written []string
+	origWriteFile := writeSysfsFile
+	defer func() { writeSysfsFile = origWriteFile }()
+	writeSysfsFile = func(name string, data []byte, perm os.FileMode) error {
+		written = append(written, filepath.Base(name))
+		return origWriteFile(name, data, perm)
+	}
+	assert.NoError(t, uncore.write(uncoreDirName+"/package_00_die_00"))
+	assert.Equal(t, []string{
+		uncoreMaxFreqFile,
+		uncoreMinFreqFile,
+		uncoreElcFloorFreqFile,
+		uncoreElcLowThresholdFile,
+		uncoreElcHighThresholdFile,
+		uncoreElcHighEnableFile,
+	}, written)
 }
 
 func TestCpuTopology_SetUncoreFrequency(t *testing.T) {
@@ -282,23 +403,39 @@ func TestCpuPackage_getEffectiveUncore(t *testing.T) {
 
 func TestCpuDie_SetUncoreFrequency(t *testing.T) {
 	uncore := new(mockUncore)
-	uncore.On("write", uint(1), uint(0)).Return(nil)
+	uncore.On("write", "dir1").Return(nil)
 
 	pkg := new(mockCpuPackage)
-	pkg.On("getID").Return(uint(1))
 
 	die := &cpuDie{
 		parentSocket: pkg,
 		id:           0,
+		domains:      []UncoreDomain{&uncoreDomain{uncoreDomainInfo: uncoreDomainInfo{dir: "dir1"}}},
 	}
 
 	assert.NoError(t, die.SetUncore(uncore))
 
 	assert.Equal(t, uncore, die.uncore)
-	pkg.AssertExpectations(t)
+	pkg.AssertNotCalled(t, "getEffectiveUncore")
 	uncore.AssertExpectations(t)
 }
 
+func TestCpuDie_SetUncoreNoDomains(t *testing.T) {
+	pkg := new(mockCpuPackage)
+	pkg.On("getID").Return(uint(1))
+	pkg.On("getEffectiveUncore").Return(new(mockUncore))
+	die := &cpuDie{parentSocket: pkg, id: 1}
+
+	// multi die TPMI packages keep their domains at package level
+	err := die.SetUncore(new(mockUncore))
+	assert.ErrorIs(t, err, ErrFeatureUnsupported)
+	assert.ErrorContains(t, err, "die 1 of package 1 has no uncore domains")
+	assert.Nil(t, die.uncore)
+	assert.ErrorIs(t, die.setPoolUncore(new(poolMock), new(mockUncore)), ErrFeatureUnsupported)
+	assert.Empty(t, die.poolUncores)
+	assert.NoError(t, die.SetUncore(nil))
+}
+
 func TestCpuDie_getEffectiveUncore(t *testing.T) {
 	pkg := new(mockCpuPackage)
 	uncore := new(mockUncore)
@@ -319,39 +456,47 @@ func TestCpuDie_getEffectiveUncore(t *testing.T) {
 
 func TestCpuDie_applyUncore(t *testing.T) {
 	uncore := new(mockUncore)
-	uncore.On("write", uint(2), uint(2)).Return(nil)
+	uncore.On("write", "dir1").Return(nil)
+	uncore.On("write", "dir2").Return(nil)
 
 	pkg := new(mockCpuPackage)
-	pkg.On("getID").Return(uint(2))
 
 	die := &cpuDie{
 		parentSocket: pkg,
 		id:           2,
 		uncore:       uncore,
+		domains: []UncoreDomain{
+			&uncoreDomain{uncoreDomainInfo: uncoreDomainInfo{dir: "dir1"}},
+			&uncoreDomain{uncoreDomainInfo: uncoreDomainInfo{dir: "dir2"}},
+		},
 	}
 
 	assert.NoError(t, die.applyUncore())
 
-	pkg.AssertExpectations(t)
 	uncore.AssertExpectations(t)
 
+	// domain uncore takes precedence
+	domainUncore := new(mockUncore)
+	domainUncore.On("write", "dir2").Return(nil)
+	die.domains[1].(*uncoreDomain).uncore = domainUncore
+	assert.NoError(t, die.applyUncore())
+	uncore.AssertNumberOfCalls(t, "write", 3)
+	domainUncore.AssertExpectations(t)
+
 	//error writing
 	uncore = new(mockUncore)
 	expectedErr := fmt.Errorf("")
-	uncore.On("write", uint(2), uint(2)).Return(expectedErr)
-
-	pkg = new(mockCpuPackage)
-	pkg.On("getID").Return(uint(2))
+	uncore.On("write", "dir1").Return(expectedErr)
 
 	die = &cpuDie{
 		parentSocket: pkg,
 		id:           2,
 		uncore:       uncore,
+		domains:      []UncoreDomain{&uncoreDomain{uncoreDomainInfo: uncoreDomainInfo{dir: "dir1"}}},
 	}
 
 	assert.ErrorIs(t, die.applyUncore(), expectedErr)
 
-	pkg.AssertExpectations(t)
 	uncore.AssertExpectations(t)
 }
 
@@ -361,3 +506,191 @@ func TestNormalizeUncoreFrequency(t *testing.T) {
 	assert.Equal(t, uint(0), normalizeUncoreFreq(12))
 	assert.Equal(t, uint(1_100_000), normalizeUncoreFreq(1_100_001))
 }
+
+func TestArbitrateUncore(t *testing.T) {
+	origPolicy := uncorePoolPolicy
+	defer func() { uncorePoolPolicy = origPolicy }()
+
+	single := &uncoreFreq{min: 1_200_000, max: 2_000_000}
+	assert.Equal(t, single, arbitrateUncore([]Uncore{single}))
+
+	requests := []Uncore{
+		&uncoreFreq{min: 1_200_000, max: 2_000_000},
+		&uncoreFreq{min: 1_600_000, max: 1_800_000},
+	}
+	uncorePoolPolicy = UncorePoolPolicyMax
+	assert.Equal(t, &uncoreFreq{min: 1_600_000, max: 2_000_000}, arbitrateUncore(requests))
+
+	uncorePoolPolicy = UncorePoolPolicyMin
+	assert.Equal(t, &uncoreFreq{min: 1_200_000, max: 1_800_000}, arbitrateUncore(requests))
+
+	// min cannot exceed max
+	requests = []Uncore{
+		&uncoreFreq{min: 2_000_000, max: 2_400_000},
+		&uncoreFreq{min: 1_000_000, max: 1_400_000},
+	}
+	assert.Equal(t, &uncoreFreq{min: 1_000_000, max: 1_400_000}, arbitrateUncore(requests))
+
+	// elc is combined field by field, requests without elc are ignored
+	requests = []Uncore{
+		&uncoreFreq{min: 1_200_000, max: 2_000_000, elc: &UncoreElc{LowThresholdPercent: 10, HighThresholdPercent: 90, HighThresholdEnable: true, FloorFreq: 1_400_000}},
+		&uncoreFreq{min: 1_200_000, max: 2_000_000},
+		&uncoreFreq{min: 1_200_000, max: 2_000_000, elc: &UncoreElc{LowThresholdPercent: 20, HighThresholdPercent: 80, FloorFreq: 1_600_000}},
+	}
+	assert.Equal(t, &UncoreElc{LowThresholdPercent: 20, HighThresholdPercent: 90, FloorFreq: 1_400_000}, arbitrateUncore(requests).elcParams())
+	uncorePoolPolicy = UncorePoolPolicyMax
+	assert.Equal(t, &UncoreElc{LowThresholdPercent: 10, HighThresholdPercent: 80, HighThresholdEnable: true, FloorFreq: 1_600_000}, arbitrateUncore(requests).elcParams())
+	assert.Nil(t, arbitrateUncore(requests[1:2]).elcParams())
+
+	// floor is kept within limits raised or lowered by a pool without elc
+	requests = []Uncore{
+		&uncoreFreq{min: 1_200_000, max: 2_000_000, elc: &UncoreElc{LowThresholdPercent: 10, HighThresholdPercent: 90, FloorFreq: 1_400_000}},
+		&uncoreFreq{min: 1_800_000, max: 2_400_000},
+	}
+	uncorePoolPolicy = UncorePoolPolicyMax
+	merged := arbitrateUncore(requests)
+	min, max := merged.limits()
+	assert.Equal(t, uint(1_800_000), min)
+	assert.Equal(t, uint(2_400_000), max)
+	assert.Equal(t, uint(1_800_000), merged.elcParams().FloorFreq)
+	// requests are not modified
+	assert.Equal(t, uint(1_400_000), requests[0].elcParams().FloorFreq)
+
+	requests = []Uncore{
+		&uncoreFreq{min: 1_200_000, max: 2_000_000, elc: &UncoreElc{LowThresholdPercent: 10, HighThresholdPercent: 90, FloorFreq: 1_800_000}},
+		&uncoreFreq{min: 1_000_000, max: 1_600_000},
+	}
+	uncorePoolPolicy = UncorePoolPolicyMin
+	merged = arbitrateUncore(requests)
+	assert.Equal(t, &uncoreFreq{min: 1_000_000, max: 1_600_000, elc: &UncoreElc{LowThresholdPercent: 10, HighThresholdPercent: 90, FloorFreq: 1_600_000}}, merged)
+}
+
+func TestCpuDie_setPoolUncore(t *testing.T) {
+	pool1 := new(poolMock)
+	uncore1 := new(mockUncore)
+	uncore1.On("write", "dir").Return(nil)
+
+	pkg := new(mockCpuPackage)
+	die := &cpuDie{
+		parentSocket: pkg,
+		domains:      []UncoreDomain{&uncoreDomain{uncoreDomainInfo: uncoreDomainInfo{dir: "dir"}}},
+	}
+
+	// first request is applied
+	assert.NoError(t, die.setPoolUncore(pool1, uncore1))
+	assert.Equal(t, uncore1, die.getEffectiveUncore())
+	uncore1.AssertNumberOfCalls(t, "write", 1)
+
+	// same request, nothing is written
+	assert.NoError(t, die.setPoolUncore(pool1, uncore1))
+	uncore1.AssertNumberOfCalls(t, "write", 1)
+
+	// die uncore takes precedence
+	dieUncore := new(mockUncore)
+	die.uncore = dieUncore
+	assert.Equal(t, dieUncore, die.getEffectiveUncore())
+	die.uncore = nil
+
+	// removing request falls back to package uncore
+	pkgUncore := new(mockUncore)
+	pkgUncore.On("write", "dir").Return(nil)
+	pkg.On("getEffectiveUncore").Return(pkgUncore)
+	assert.NoError(t, die.setPoolUncore(pool1, nil))
+	assert.Empty(t, die.poolUncores)
+	pkgUncore.AssertExpectations(t)
+
+	// removing not existing request does nothing
+	assert.NoError(t, die.setPoolUncore(pool1, nil))
+	pkgUncore.AssertNumberOfCalls(t, "write", 1)
+}
+
+func TestPoolImpl_SetUncore(t *testing.T) {
+	pool := &poolImpl{mutex: &sync.Mutex{}}
+	assert.ErrorIs(t, pool.SetUncore(&uncoreFreq{}), uninitialisedErr)
+
+	host, teardown := setupAllocatorTest()
+	defer teardown()
+	uncoreFiles := map[string]map[string]string{}
+	for _, pkgDie := range []string{"package_00_die_00", "package_00_die_01", "package_01_die_00", "package_01_die_01"} {
+		uncoreFiles[pkgDie] = map[string]string{"Max": "2400000", "Min": "1200000", "initMax": "2400000", "initMin": "1200000"}
+	}
+	defer setupUncoreTests(uncoreFiles, "")()
+	defaultUncore.min = 1_200_000
+	defaultUncore.max = 2_400_000
+	domains, err := discoverUncoreDomains()
+	assert.NoError(t, err)
+	host.topology.(*cpuTopology).addUncoreDomains(domains)
+
+	assert.Error(t, host.GetReservedPool().SetUncore(&uncoreFreq{}))
+
+	// pool covering die 0 of package 0
+	pool1, err := host.AddExclusivePool("pool1")
+	assert.NoError(t, err)
+	assert.NoError(t, pool1.MoveCpuIDs([]uint{0, 8}))
+	uncore1 := &uncoreFreq{min: 1_600_000, max: 2_000_000}
+	assert.NoError(t, pool1.SetUncore(uncore1))
+	assert.Equal(t, uncore1, pool1.GetUncore())
+	assert.Equal(t, uncore1, host.Topology().Package(0).Die(0).getEffectiveUncore())
+	value, _ := readUncoreProperty(0, 0, uncoreMaxFreqFile)
+	assert.Equal(t, uint(2_000_000), value)
+	value, _ = readUncoreProperty(0, 1, uncoreMaxFreqFile)
+	assert.Equal(t, uint(2_400_000), value)
+
+	// second pool sharing the die, highest frequencies win
+	pool2, err := host.AddExclusivePool("pool2")
+	assert.NoError(t, err)
+	assert.NoError(t, pool2.MoveCpuIDs([]uint{1}))
+	assert.NoError(t, pool2.SetUncore(&uncoreFreq{min: 1_400_000, max: 2_200_000}))
+	value, _ = readUncoreProperty(0, 0, uncoreMaxFreqFile)
+	assert.Equal(t, uint(2_200_000), value)
+	value, _ = readUncoreProperty(0, 0, uncoreMinFreqFile)
+	assert.Equal(t, uint(1_600_000), value)
+
+	// cpu moved to another die is re-evaluated
+	assert.NoError(t, pool1.MoveCpuIDs([]uint{2}))
+	value, _ = readUncoreProperty(0, 1, uncoreMaxFreqFile)
+	assert.Equal(t, uint(2_000_000), value)
+
+	// removed pools release their requests
+	assert.NoError(t, pool2.Remove())
+	value, _ = readUncoreProperty(0, 0, uncoreMaxFreqFile)
+	assert.Equal(t, uint(2_000_000), value)
+	assert.NoError(t, pool1.Remove())
+	for _, die := range []uint{0, 1} {
+		value, _ = readUncoreProperty(0, die, uncoreMaxFreqFile)
+		assert.Equal(t, uint(2_400_000), value)
+	}
+}
+
+func TestPoolImpl_SetUncoreNoDomains(t *testing.T) {
+	host, teardown := setupAllocatorTest()
+	defer teardown()
+	// dies of package 1 have no uncore domains
+	uncoreFiles := map[string]map[string]string{}
+	for _, pkgDie := range []string{"package_00_die_00", "package_00_die_01"} {
+		uncoreFiles[pkgDie] = map[string]string{"Max": "2400000", "Min": "1200000", "initMax": "2400000", "initMin": "1200000"}
+	}
+	defer setupUncoreTests(uncoreFiles, "")()
+	defaultUncore.min = 1_200_000
+	defaultUncore.max = 2_400_000
+	domains, err := discoverUncoreDomains()
+	assert.NoError(t, err)
+	host.topology.(*cpuTopology).addUncoreDomains(domains)
+
+	pool, err := host.AddExclusivePool("pool1")
+	assert.NoError(t, err)
+	assert.NoError(t, pool.MoveCpuIDs([]uint{0, 4}))
+	err = pool.SetUncore(&uncoreFreq{min: 1_600_000, max: 2_000_000})
+	assert.ErrorIs(t, err, ErrFeatureUnsupported)
+	assert.ErrorContains(t, err, "die 0 of package 1 has no uncore domains")
+	assert.Nil(t, pool.GetUncore())
+	value, _ := readUncoreProperty(0, 0, uncoreMaxFreqFile)
+	assert.Equal(t, uint(2_400_000), value)
+
+	// pool on dies with domains only
+	assert.NoError(t, host.GetSharedPool().MoveCpuIDs([]uint{4}))
+	assert.NoError(t, pool.SetUncore(&uncoreFreq{min: 1_600_000, max: 2_000_000}))
+	value, _ = readUncoreProperty(0, 0, uncoreMaxFreqFile)
+	assert.Equal(t, uint(2_000_000), value)
+	assert.NoError(t, pool.SetUncore(nil))
+}